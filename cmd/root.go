@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -10,18 +15,27 @@ import (
 
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/version"
 
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/cluster"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/health"
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/k8s"
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/metrics"
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/report"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/supervisor"
 	"github.com/dimiro1/banner"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 var (
 	logger            *zap.Logger
 	kubeConfigPathArr []string
 	opts              *options.KubePodTerminatorOptions
+	checker           *health.Checker
+	collector         = report.NewCollector()
 	ver               = version.Get()
 )
 
@@ -30,17 +44,110 @@ func init() {
 
 	rootCmd.Flags().BoolVarP(&opts.InCluster, "in-cluster", "", false, "specify if kube-pod-terminator is running in cluster")
 	rootCmd.Flags().StringVarP(&opts.KubeConfigPaths, "kubeconfig-paths", "", filepath.Join(os.Getenv("HOME"), ".kube", "config"),
-		"comma separated list of kubeconfig file paths to access with the cluster")
-	rootCmd.Flags().StringVarP(&opts.Namespace, "namespace", "", "all", "target namespace to run on")
+		"comma separated list of kubeconfig file paths to access with the cluster, KUBECONFIG is used if it is "+
+			"not set")
+	rootCmd.Flags().StringVarP(&opts.KubeConfigDir, "kubeconfig-dir", "", "", "directory whose files are each "+
+		"treated as a kubeconfig, files which are added to or removed from it are picked up when one-shot is false")
+	rootCmd.Flags().StringVarP(&opts.Contexts, "contexts", "", "", "comma separated list of kubeconfig contexts "+
+		"to run on for each kubeconfig path, all means all contexts, empty means the current-context")
+	rootCmd.Flags().StringVarP(&opts.Namespace, "namespace", "", "all", "comma separated list of target namespace "+
+		"names or glob patterns to run on, all means all namespaces")
+	rootCmd.Flags().StringVarP(&opts.ExcludeNamespaces, "exclude-namespaces", "", "", "comma separated list of "+
+		"namespace names or glob patterns which are never touched, e.g. kube-system")
+	rootCmd.Flags().StringVarP(&opts.NamespaceSelector, "namespace-selector", "", "", "label selector which "+
+		"restricts the target namespaces, e.g. terminator.io/enabled=true")
+	rootCmd.Flags().StringVarP(&opts.PodSelector, "pod-selector", "", "", "label selector which restricts the "+
+		"target pods, e.g. team=batch")
+	rootCmd.Flags().StringVarP(&opts.PodFieldSelector, "pod-field-selector", "", "", "field selector which "+
+		"restricts the target pods, e.g. spec.nodeName=node-1")
 	rootCmd.Flags().Int32VarP(&opts.TickerIntervalMinutes, "ticker-interval-minutes", "", 5, "interval of scheduled job to run")
 	rootCmd.Flags().Int64VarP(&opts.GracePeriodSeconds, "grace-period-seconds", "", 30, "grace period to delete target pods")
+	rootCmd.Flags().BoolVarP(&opts.UseEviction, "use-eviction", "", false, "evict live target pods through the "+
+		"Eviction API instead of deleting them, so that PodDisruptionBudgets are respected")
+	rootCmd.Flags().BoolVarP(&opts.ForceDelete, "force-delete", "", true, "delete target pods with zero grace "+
+		"period if they still exist after the normal deletion")
+	rootCmd.Flags().BoolVarP(&opts.RemoveFinalizers, "remove-finalizers", "", false, "remove finalizers of target "+
+		"pods if they still exist after the forced deletion")
+	rootCmd.Flags().Int32VarP(&opts.EscalationWaitSeconds, "escalation-wait-seconds", "", 10, "seconds to wait "+
+		"for a target pod to be deleted before escalating to the next deletion step, added to the grace period for "+
+		"the normal deletion")
 	rootCmd.Flags().BoolVarP(&opts.TerminateEvicted, "terminate-evicted", "", true, "terminate evicted pods in specified namespaces")
+	rootCmd.Flags().Int32VarP(&opts.EvictedStateMinutes, "evicted-state-minutes", "", 0, "terminate evicted pods "+
+		"which are evicted more than that value")
 	rootCmd.Flags().Int32VarP(&opts.TerminatingStateMinutes, "terminating-state-minutes", "", 30, "terminate stucked pods "+
 		"in terminating state which are more than that value")
+	rootCmd.Flags().BoolVarP(&opts.TerminateCrashLoop, "terminate-crashloop", "", false, "terminate pods in "+
+		"CrashLoopBackOff state in specified namespaces")
+	rootCmd.Flags().Int32VarP(&opts.CrashLoopStateMinutes, "crashloop-state-minutes", "", 60, "terminate pods "+
+		"in CrashLoopBackOff state which are more than that value")
+	rootCmd.Flags().Int32VarP(&opts.CrashLoopRestartCount, "crashloop-restart-count", "", 0, "terminate pods "+
+		"in CrashLoopBackOff state which restarted at least that many times regardless of the duration, 0 disables it")
+	rootCmd.Flags().BoolVarP(&opts.TerminateImagePullErrors, "terminate-image-pull-errors", "", false, "terminate "+
+		"pods in ImagePullBackOff, ErrImagePull or InvalidImageName state in specified namespaces")
+	rootCmd.Flags().Int32VarP(&opts.ImagePullErrorStateMinutes, "image-pull-error-state-minutes", "", 30, "terminate "+
+		"pods in ImagePullBackOff, ErrImagePull or InvalidImageName state which are more than that value")
+	rootCmd.Flags().Int32VarP(&opts.SucceededRetentionMinutes, "succeeded-retention-minutes", "", 0, "terminate "+
+		"pods in Succeeded phase which are completed more than that value, 0 disables it")
+	rootCmd.Flags().Int32VarP(&opts.FailedRetentionMinutes, "failed-retention-minutes", "", 0, "terminate "+
+		"pods in Failed phase which are completed more than that value, 0 disables it")
+	rootCmd.Flags().Int32VarP(&opts.MaxDeletionsPerRun, "max-deletions-per-run", "", 0, "maximum number of pods "+
+		"which are terminated in a run, or between two ticks in the long-running mode, the rest is left to the next "+
+		"runs, 0 disables it")
+	rootCmd.Flags().Int32VarP(&opts.MaxNamespaceDeletionPercentage, "max-namespace-deletion-percentage", "", 0,
+		"maximum percentage of pods in a namespace which are terminated in a run, at least one pod per namespace is "+
+			"allowed, 0 disables it")
+	rootCmd.Flags().Int32VarP(&opts.AbortCandidateThreshold, "abort-candidate-threshold", "", 0, "abort the run "+
+		"without terminating any pod if more pods than that value are matched, 0 disables it")
+	rootCmd.Flags().IntVarP(&opts.Workers, "workers", "", 5, "number of workers which terminate the matched pods "+
+		"concurrently")
+	rootCmd.Flags().Float32VarP(&opts.DeleteQPS, "delete-qps", "", 5, "maximum number of deletion requests per "+
+		"second of a cluster across all workers and runs, 0 disables it")
+	rootCmd.Flags().IntVarP(&opts.DeleteBurst, "delete-burst", "", 10, "number of deletion requests which can be "+
+		"sent at once before delete-qps is enforced")
+	rootCmd.Flags().Float32VarP(&opts.KubeAPIQPS, "kube-api-qps", "", 20, "maximum number of requests per "+
+		"second to the kube-apiserver of each cluster")
+	rootCmd.Flags().IntVarP(&opts.KubeAPIBurst, "kube-api-burst", "", 40, "number of requests which can be sent "+
+		"to the kube-apiserver of each cluster at once before kube-api-qps is enforced")
+	rootCmd.Flags().Int32VarP(&opts.ListTimeoutSeconds, "list-timeout-seconds", "", 60, "timeout of the list "+
+		"calls to the kube-apiserver in a run, 0 disables it")
+	rootCmd.Flags().Int32VarP(&opts.DrainTimeoutSeconds, "drain-timeout-seconds", "", 30, "seconds that the "+
+		"in-flight deletions are given to finish on SIGINT or SIGTERM before they are cancelled")
+	rootCmd.Flags().Int32VarP(&opts.RestartBackoffInitialSeconds, "restart-backoff-initial-seconds", "", 5,
+		"seconds to wait before restarting the failed worker of a kubeconfig when one-shot is false, doubled on "+
+			"each consecutive failure")
+	rootCmd.Flags().Int32VarP(&opts.RestartBackoffMaxSeconds, "restart-backoff-max-seconds", "", 300, "maximum "+
+		"seconds to wait before restarting the failed worker of a kubeconfig when one-shot is false")
 	rootCmd.Flags().BoolVarP(&opts.OneShot, "one-shot", "", true, "specifier to run kube-pod-terminator "+
 		"only one time instead of continuously running in the background. should be true if you are using app as CLI.")
 	rootCmd.Flags().StringVarP(&opts.BannerFilePath, "banner-file-path", "", "build/ci/banner.txt",
 		"relative path of the banner file")
+	rootCmd.Flags().StringVarP(&opts.DryRun, "dry-run", "", options.DryRunNone, "dry-run mode, must be one of none, "+
+		"client or server. client only reports the target pods, server also sends the deletion requests with DryRun=All")
+	rootCmd.Flags().StringVarP(&opts.Output, "output", "o", "", "print the report of the candidate pods of each "+
+		"cluster to stdout when one-shot is true, must be one of table, json or yaml. logs are written to stderr "+
+		"if it is set")
+	rootCmd.Flags().BoolVarP(&opts.DetailedExitCodes, "detailed-exit-codes", "", false, "exit with 2 if pods are "+
+		"found, 3 on partial failures and 4 if a cluster is unreachable when one-shot is true, instead of always 0")
+	rootCmd.Flags().StringVarP(&opts.ConfigFile, "config", "", "", "path of the YAML or JSON config file which "+
+		"contains the per-namespace policies, command line flags act as the defaults of the config file")
+	rootCmd.Flags().IntVarP(&opts.MetricsPort, "metrics-port", "", 8080, "port of the Prometheus /metrics endpoint "+
+		"which is served when one-shot is false")
+	rootCmd.Flags().IntVarP(&opts.HealthPort, "health-port", "", 8081, "port of the /healthz and /readyz "+
+		"endpoints which are served when one-shot is false")
+	rootCmd.Flags().Int32VarP(&opts.HealthStalenessMinutes, "health-staleness-minutes", "", 10, "minutes on top "+
+		"of the ticker interval after which a cluster worker without a completed tick is treated as wedged")
+	rootCmd.Flags().BoolVarP(&opts.LeaderElect, "leader-elect", "", false, "elect a leader with a Lease in each "+
+		"cluster when one-shot is false, so that only the leader replica terminates the pods")
+	rootCmd.Flags().StringVarP(&opts.LeaderElectionLeaseName, "leader-election-lease-name", "",
+		"kube-pod-terminator", "name of the Lease which is used for the leader election")
+	rootCmd.Flags().StringVarP(&opts.LeaderElectionNamespace, "leader-election-namespace", "", "default",
+		"namespace of the Lease which is used for the leader election")
+	rootCmd.Flags().Int32VarP(&opts.LeaderElectionLeaseDurationSeconds, "leader-election-lease-duration-seconds", "",
+		15, "seconds that the standby replicas wait before taking over the Lease of a leader")
+	rootCmd.Flags().Int32VarP(&opts.LeaderElectionRenewDeadlineSeconds, "leader-election-renew-deadline-seconds", "",
+		10, "seconds that the leader retries renewing the Lease before giving up the leadership")
+	rootCmd.Flags().Int32VarP(&opts.LeaderElectionRetryPeriodSeconds, "leader-election-retry-period-seconds", "",
+		2, "seconds to wait between the tries of the Lease actions")
 	rootCmd.Flags().BoolVarP(&opts.VerboseLog, "verbose", "v", false, "verbose output of the logging library (default false)")
 
 	if err := rootCmd.Flags().MarkHidden("banner-file-path"); err != nil {
@@ -60,14 +167,35 @@ var rootCmd = &cobra.Command{
 connects to the **kube-apiserver**, discovers Terminating pods which are in Terminating status and destroys them. This tool can also be
 used for Evicted state pods.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// stdout is reserved for the report, so that it can be piped into tools like jq
+		bannerOutput := os.Stdout
+		if opts.Output != "" {
+			logging.SetOutput(os.Stderr)
+			bannerOutput = os.Stderr
+		}
+
+		if opts.ConfigFile != "" {
+			configOpts, err := options.LoadConfig(opts.ConfigFile, opts)
+			if err != nil {
+				logger.Fatal("fatal error occurred while loading config file", zap.String("configFile", opts.ConfigFile),
+					zap.String("error", err.Error()))
+			}
+
+			opts = configOpts
+		}
+
 		log.Println(opts.VerboseLog)
 		if opts.VerboseLog {
 			logging.Atomic.SetLevel(zap.DebugLevel)
 		}
 
+		if err := opts.Validate(); err != nil {
+			logger.Fatal("fatal error occurred while validating options", zap.String("error", err.Error()))
+		}
+
 		if _, err := os.Stat(opts.BannerFilePath); err == nil {
 			bannerBytes, _ := os.ReadFile(opts.BannerFilePath)
-			banner.Init(os.Stdout, true, false, strings.NewReader(string(bannerBytes)))
+			banner.Init(bannerOutput, true, false, strings.NewReader(string(bannerBytes)))
 		}
 
 		logger.Info("kube-pod-terminator is started",
@@ -78,51 +206,244 @@ used for Evicted state pods.`,
 			zap.String("gitCommit", ver.GitCommit),
 			zap.String("buildDate", ver.BuildDate))
 
-		// our application logic starts right here
-		kubeConfigPathArr = strings.Split(opts.KubeConfigPaths, ",")
-		exitSignal := make(chan os.Signal)
-		for _, path := range kubeConfigPathArr {
-			go func(p string) {
-				logger = logger.With(zap.String("kubeConfigPath", p))
-				logger.Info("starting generating clientset for kubeconfig")
-				restConfig, err := k8s.GetConfig(p, opts.InCluster)
-				if err != nil {
-					logger.Fatal("fatal error occurred while getting k8s config", zap.String("error", err.Error()))
-				}
+		// SIGINT and SIGTERM cancel ctx, so that the workers stop after the in-flight deletions are drained
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-				clientSet, err := k8s.GetClientSet(restConfig)
-				if err != nil {
-					logger.Fatal("fatal error occurred while getting clientset", zap.String("error", err.Error()))
-				}
+		// in the long-running mode, config file is reloaded on changes and on SIGHUP. workers get the current
+		// options from the reloader on each run, so the new options are swapped in between the runs
+		reloader := options.NewReloader(opts.ConfigFile, options.GetKubePodTerminatorOptions(), opts)
+		if !opts.OneShot && opts.ConfigFile != "" {
+			if err := reloader.Watch(ctx.Done(), onConfigReload); err != nil {
+				logger.Fatal("fatal error occurred while watching config file", zap.String("configFile", opts.ConfigFile),
+					zap.String("error", err.Error()))
+			}
+		}
+
+		checker = health.NewChecker(time.Duration(opts.HealthStalenessMinutes) * time.Minute)
+
+		// our application logic starts right here. each kubeconfig is run as an isolated worker, so that a failing
+		// cluster is restarted with backoff without affecting the others
+		clusters := supervisor.New(logger, time.Duration(opts.RestartBackoffInitialSeconds)*time.Second,
+			time.Duration(opts.RestartBackoffMaxSeconds)*time.Second, !opts.OneShot)
+		if !opts.OneShot {
+			go serve(ctx, "metrics", opts.MetricsPort, metrics.Handler())
+			go serve(ctx, "health", opts.HealthPort, getHealthHandler(clusters))
+		}
+
+		kubeConfigPathArr = cluster.GetKubeConfigPaths(opts, cmd.Flags().Changed("kubeconfig-paths"))
+		if err := reconcileClusters(ctx, clusters, reloader); err != nil {
+			logger.Fatal("fatal error occurred while resolving kubeconfigs", zap.String("error", err.Error()))
+		}
 
-				k8s.Run(opts, clientSet, restConfig.Host)
-				if opts.OneShot {
-					exitSignal <- syscall.SIGTERM
-					return
+		// in the long-running mode, clusters which are added to or removed from the kubeconfig directory are
+		// picked up without a restart
+		if !opts.OneShot && opts.KubeConfigDir != "" {
+			if err := k8s.WatchKubeConfigDir(ctx.Done(), opts.KubeConfigDir, func(err error) {
+				if err == nil {
+					err = reconcileClusters(ctx, clusters, reloader)
 				}
 
-				ticker := time.NewTicker(time.Duration(opts.TickerIntervalMinutes) * time.Minute)
-				for range ticker.C {
-					k8s.Run(opts, clientSet, restConfig.Host)
+				if err != nil {
+					logger.Error("kubeconfig directory is rejected, keeping the current clusters",
+						zap.String("kubeConfigDir", opts.KubeConfigDir), zap.String("error", err.Error()))
 				}
-			}(path)
+			}); err != nil {
+				logger.Fatal("fatal error occurred while watching kubeconfig directory",
+					zap.String("kubeConfigDir", opts.KubeConfigDir), zap.String("error", err.Error()))
+			}
+
+			<-ctx.Done()
 		}
 
+		clusters.Wait()
 		if opts.OneShot {
-			signalCounter := 0
-			for range exitSignal {
-				signalCounter++
-				if signalCounter == len(kubeConfigPathArr) {
-					logger.Info("all goroutines sent a SIGTERM, exiting")
-					os.Exit(0)
-				}
-			}
+			exitOneShot(clusters)
 		}
 
-		select {}
+		logger.Info("all workers are stopped, exiting")
 	},
 }
 
+// reconcileClusters starts a worker for each cluster of the kubeconfig paths and the kubeconfig directory which is
+// not started yet, and stops the workers of the clusters which no longer exist
+func reconcileClusters(ctx context.Context, clusters *supervisor.Supervisor, reloader *options.Reloader) error {
+	return cluster.Reconcile(ctx, clusters, kubeConfigPathArr, opts,
+		func(ctx context.Context, logger *zap.Logger, target cluster.Target) error {
+			return runCluster(ctx, logger, target, reloader)
+		}, logger)
+}
+
+// runCluster runs the business logic for the cluster of the target until ctx is done, only once if one-shot is
+// enabled. It returns an error if the worker of the cluster can not be started.
+func runCluster(ctx context.Context, logger *zap.Logger, target cluster.Target, reloader *options.Reloader) error {
+	logger.Info("starting generating clientset for kubeconfig", zap.String("kubeConfigPath", target.KubeConfigPath),
+		zap.String("context", target.KubeContext))
+	restConfig, err := k8s.GetConfig(target.KubeConfigPath, target.KubeContext, opts.InCluster, opts.KubeAPIQPS,
+		opts.KubeAPIBurst)
+	if err != nil {
+		return fmt.Errorf("getting k8s config: %w", err)
+	}
+
+	clientSet, err := k8s.GetClientSet(restConfig)
+	if err != nil {
+		return fmt.Errorf("getting clientset: %w", err)
+	}
+
+	// clusters are labeled by their context names if they are selected with --contexts, by their API server URLs
+	// otherwise
+	clusterName := restConfig.Host
+	if target.KubeContext != "" {
+		clusterName = target.KubeContext
+	}
+
+	// pending events are flushed before the worker returns, so that they are not lost on exit or on restart
+	recorder, stopRecorder := k8s.NewEventRecorder(clientSet)
+	defer stopRecorder()
+
+	if opts.OneShot {
+		collector.Add(k8s.Run(ctx, opts, clientSet, k8s.NewAPIPodLister(clientSet), recorder,
+			k8s.NewDeleteRateLimiter(opts), clusterName))
+		return nil
+	}
+
+	// a worker which is waiting to be restarted must not be treated as wedged by the liveness probe
+	defer checker.Unregister(clusterName)
+
+	return runDaemon(ctx, logger, clientSet, recorder, clusterName, reloader)
+}
+
+// runDaemon runs the business logic for the cluster on each tick and on each matched pod in the informer cache
+// until ctx is done
+func runDaemon(ctx context.Context, logger *zap.Logger, clientSet kubernetes.Interface, recorder record.EventRecorder,
+	clusterName string, reloader *options.Reloader) error {
+	// in the long-running mode, pods are served from the local cache of a shared informer and the
+	// ticker is only the evaluation interval over that cache
+	runOpts := reloader.Get()
+	podLister, err := k8s.NewInformerPodLister(ctx, clientSet, runOpts)
+	if err != nil {
+		return fmt.Errorf("starting pod informer: %w", err)
+	}
+
+	// the worker is registered only once its cache is synced, a worker which can not sync is restarted by the
+	// supervisor with backoff instead of failing the liveness probe of the whole terminator
+	checker.Register(clusterName, cluster.GetTickerInterval(runOpts))
+
+	w := cluster.NewWorker(logger, clientSet, podLister, recorder, checker, clusterName, runOpts)
+
+	// standby replicas keep their informer caches warm but do not run until they acquire the Lease
+	if opts.LeaderElect {
+		election, err := k8s.NewLeaderElection(clientSet, opts, "", logger)
+		if err != nil {
+			return fmt.Errorf("starting leader election: %w", err)
+		}
+
+		go election.Run(ctx)
+		w.SetLeader(election.IsLeader)
+	}
+
+	w.RunIfLeader(ctx, runOpts)
+
+	ticker := time.NewTicker(cluster.GetTickerInterval(runOpts))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("shutting down the worker of the cluster")
+			return nil
+		case <-ticker.C:
+			w.ResetBudget()
+		case <-podLister.Updates():
+			logger.Debug("a pod is matched by the detectors in the informer cache, running now")
+		}
+
+		if current := reloader.Get(); current != runOpts {
+			if current.TickerIntervalMinutes != runOpts.TickerIntervalMinutes {
+				ticker.Reset(cluster.GetTickerInterval(current))
+			}
+
+			w.SetOptions(runOpts, current)
+			runOpts = current
+		}
+
+		w.RunIfLeader(ctx, runOpts)
+	}
+}
+
+// exitOneShot writes the reports of the clusters to stdout if an output format is set and exits with the most severe
+// exit code of the clusters if the detailed exit codes are enabled, so that CronJobs and CI pipelines can act on it. Clusters whose workers are failed before
+// running are treated as unreachable. It must be called after the workers are stopped, since they flush their
+// pending events on return and os.Exit would drop them.
+func exitOneShot(clusters *supervisor.Supervisor) {
+	for _, status := range clusters.Statuses() {
+		if status.State == supervisor.StateFailed {
+			collector.Add(&k8s.Report{Cluster: status.Name, Result: k8s.RunFailure, Error: status.LastError,
+				Pods: []k8s.PodReport{}}, fmt.Errorf("%w: %s", k8s.ErrClusterUnreachable, status.LastError))
+		}
+	}
+
+	if failed := clusters.Failed(); len(failed) > 0 {
+		logger.Error("workers of some clusters are failed", zap.Strings("workers", failed))
+	}
+
+	if opts.Output != "" {
+		if err := report.Write(os.Stdout, opts.Output, collector.Reports()); err != nil {
+			logger.Fatal("fatal error occurred while writing report", zap.String("output", opts.Output),
+				zap.String("error", err.Error()))
+		}
+	}
+
+	exitCode := collector.ExitCode(opts.DetailedExitCodes)
+	logger.Info("all workers are stopped, exiting", zap.Int("exitCode", exitCode))
+	os.Exit(exitCode)
+}
+
+// getHealthHandler returns the http.Handler which serves the probes of the checker and the statuses of the cluster
+// workers on /status
+func getHealthHandler(clusters *supervisor.Supervisor) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/status", clusters.Handler())
+	mux.Handle("/", checker.Handler())
+
+	return mux
+}
+
+// serve serves the handler on the port until ctx is done, it exits the application if the server fails
+func serve(ctx context.Context, name string, port int, handler http.Handler) {
+	logger.Info("starting http server", zap.String("server", name), zap.Int("port", port))
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("fatal error occurred while serving http", zap.String("server", name),
+			zap.String("error", err.Error()))
+	}
+}
+
+// onConfigReload logs the result of a config file reload and applies the log level of the new options
+func onConfigReload(newOpts *options.KubePodTerminatorOptions, err error) {
+	if err != nil {
+		logger.Error("config file is rejected, keeping the current options", zap.String("configFile", opts.ConfigFile),
+			zap.String("error", err.Error()))
+		return
+	}
+
+	if newOpts.VerboseLog {
+		logging.Atomic.SetLevel(zap.DebugLevel)
+	} else {
+		logging.Atomic.SetLevel(zap.InfoLevel)
+	}
+
+	logger.Info("config file is reloaded, new options will be used from the next run",
+		zap.String("configFile", opts.ConfigFile))
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
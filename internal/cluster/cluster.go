@@ -0,0 +1,147 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/k8s"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/supervisor"
+	"go.uber.org/zap"
+)
+
+// Target is a cluster which is run by a supervised worker, the current-context of the kubeconfig is used if
+// KubeContext is empty
+type Target struct {
+	Name           string
+	KubeConfigPath string
+	KubeContext    string
+	// Err is the error which the kubeconfig is rejected with, the worker of the target fails with it instead of
+	// running, so that the kubeconfig shows up as failed without affecting the other clusters
+	Err error
+}
+
+// RunFunc runs the business logic for the cluster of the target until ctx is done
+type RunFunc func(ctx context.Context, logger *zap.Logger, target Target) error
+
+// GetKubeConfigPaths returns the kubeconfig paths of the options, pathsChanged tells if they are given with
+// --kubeconfig-paths. If they are not, KUBECONFIG is used as a single kubeconfig which is merged like kubectl does,
+// and the default path is only used if there is no kubeconfig directory either.
+func GetKubeConfigPaths(opts *options.KubePodTerminatorOptions, pathsChanged bool) []string {
+	kubeConfigPaths := opts.KubeConfigPaths
+	if !pathsChanged {
+		if env := os.Getenv("KUBECONFIG"); env != "" {
+			kubeConfigPaths = env
+		} else if opts.KubeConfigDir != "" {
+			return nil
+		}
+	}
+
+	var paths []string
+	for _, path := range strings.Split(kubeConfigPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// GetTargets returns a target for each kubeconfig path, or for each selected context of each kubeconfig path if
+// contexts is set. Workers of the contexts are named by the context names, so they must be unique. A kubeconfig whose
+// contexts cannot be read, or which has a context of another kubeconfig, is returned as a single target with Err,
+// which is named by its path.
+func GetTargets(paths []string, contexts string) []Target {
+	sorted := slices.Clone(paths)
+	sort.Strings(sorted)
+
+	var targets []Target
+	names := make(map[string]string)
+	for _, path := range slices.Compact(sorted) {
+		if contexts == "" {
+			targets = append(targets, Target{Name: path, KubeConfigPath: path})
+			continue
+		}
+
+		kubeContexts, err := getContexts(path, contexts, names)
+		if err != nil {
+			targets = append(targets, Target{Name: path, KubeConfigPath: path, Err: err})
+			continue
+		}
+
+		for _, kubeContext := range kubeContexts {
+			names[kubeContext] = path
+			targets = append(targets, Target{Name: kubeContext, KubeConfigPath: path, KubeContext: kubeContext})
+		}
+	}
+
+	return targets
+}
+
+// getContexts returns the selected contexts of the kubeconfig, it returns an error if any of them is already taken by
+// another kubeconfig in names
+func getContexts(path, contexts string, names map[string]string) ([]string, error) {
+	kubeContexts, err := k8s.GetContexts(path, contexts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kubeContext := range kubeContexts {
+		if other, ok := names[kubeContext]; ok {
+			return nil, fmt.Errorf("context %s exists in both kubeconfig %s and %s", kubeContext, other, path)
+		}
+	}
+
+	return kubeContexts, nil
+}
+
+// Reconcile starts a worker with run for each cluster of the kubeconfig paths and the kubeconfig directory of the
+// options which is not started yet, and stops the workers of the clusters which no longer exist. The workers of the
+// rejected kubeconfigs are started too, they fail with the error of the kubeconfig. An error is returned only if the
+// kubeconfig directory cannot be listed.
+func Reconcile(ctx context.Context, clusters *supervisor.Supervisor, paths []string,
+	opts *options.KubePodTerminatorOptions, run RunFunc, logger *zap.Logger) error {
+	if opts.KubeConfigDir != "" {
+		dirPaths, err := k8s.ListKubeConfigDir(opts.KubeConfigDir)
+		if err != nil {
+			return err
+		}
+
+		paths = append(slices.Clone(paths), dirPaths...)
+	}
+
+	targets := GetTargets(paths, opts.Contexts)
+	current := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		target := target
+		current[target.Name] = struct{}{}
+		if target.Err != nil {
+			if clusters.Start(ctx, target.Name, func(context.Context, *zap.Logger) error {
+				return target.Err
+			}) {
+				logger.Error("kubeconfig is rejected, its worker is failed", zap.String("worker", target.Name),
+					zap.String("error", target.Err.Error()))
+			}
+
+			continue
+		}
+
+		if clusters.Start(ctx, target.Name, func(ctx context.Context, logger *zap.Logger) error {
+			return run(ctx, logger, target)
+		}) {
+			logger.Info("started the worker of the cluster", zap.String("worker", target.Name))
+		}
+	}
+
+	for _, name := range clusters.Names() {
+		if _, ok := current[name]; !ok && clusters.Stop(name) {
+			logger.Info("stopped the worker of the removed cluster", zap.String("worker", name))
+		}
+	}
+
+	return nil
+}
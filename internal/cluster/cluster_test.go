@@ -0,0 +1,167 @@
+package cluster
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/supervisor"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestGetKubeConfigPaths(t *testing.T) {
+	cases := []struct {
+		caseName, kubeConfigPaths, kubeConfigDir, env string
+		pathsChanged                                  bool
+		expected                                      []string
+	}{
+		{"case1", "/tmp/kubeconfig1, /tmp/kubeconfig2,", "", "", true, []string{"/tmp/kubeconfig1", "/tmp/kubeconfig2"}},
+		{"case2", "/tmp/kubeconfig1", "", "/tmp/kubeconfig3", true, []string{"/tmp/kubeconfig1"}},
+		{"case3", "/tmp/kubeconfig1", "", "/tmp/kubeconfig3", false, []string{"/tmp/kubeconfig3"}},
+		{"case4", "/tmp/kubeconfig1", "", "", false, []string{"/tmp/kubeconfig1"}},
+		{"case5", "/tmp/kubeconfig1", "/tmp/kubeconfigs", "", false, nil},
+		{"case6", "/tmp/kubeconfig1", "/tmp/kubeconfigs", "", true, []string{"/tmp/kubeconfig1"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			t.Setenv("KUBECONFIG", tc.env)
+			opts := &options.KubePodTerminatorOptions{KubeConfigPaths: tc.kubeConfigPaths,
+				KubeConfigDir: tc.kubeConfigDir}
+			assert.Equal(t, tc.expected, GetKubeConfigPaths(opts, tc.pathsChanged))
+		})
+	}
+}
+
+func TestGetTargets(t *testing.T) {
+	kubeConfig := "../../test/kubeconfig"
+	multiContextKubeConfig := "../../test/multi_context_kubeconfig"
+	nonexistentKubeConfig := "../../test/nonexistent_kubeconfig"
+
+	cases := []struct {
+		caseName string
+		paths    []string
+		contexts string
+		expected []Target
+		rejected []string
+	}{
+		{"case1", []string{multiContextKubeConfig, kubeConfig, multiContextKubeConfig}, "", []Target{
+			{Name: kubeConfig, KubeConfigPath: kubeConfig},
+			{Name: multiContextKubeConfig, KubeConfigPath: multiContextKubeConfig},
+		}, nil},
+		{"case2", []string{multiContextKubeConfig}, "staging,production", []Target{
+			{Name: "staging", KubeConfigPath: multiContextKubeConfig, KubeContext: "staging"},
+			{Name: "production", KubeConfigPath: multiContextKubeConfig, KubeContext: "production"},
+		}, nil},
+		{"case3", []string{kubeConfig, multiContextKubeConfig}, "all", []Target{
+			{Name: "minikube", KubeConfigPath: kubeConfig, KubeContext: "minikube"},
+		}, []string{multiContextKubeConfig}},
+		{"case4", []string{multiContextKubeConfig}, "development", nil, []string{multiContextKubeConfig}},
+		{"case5", []string{nonexistentKubeConfig, multiContextKubeConfig}, "staging", []Target{
+			{Name: "staging", KubeConfigPath: multiContextKubeConfig, KubeContext: "staging"},
+		}, []string{nonexistentKubeConfig}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			var targets []Target
+			var rejected []string
+			for _, target := range GetTargets(tc.paths, tc.contexts) {
+				if target.Err != nil {
+					assert.Equal(t, target.Name, target.KubeConfigPath)
+					rejected = append(rejected, target.Name)
+					continue
+				}
+
+				targets = append(targets, target)
+			}
+
+			assert.Equal(t, tc.expected, targets)
+			assert.Equal(t, tc.rejected, rejected)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	dir := t.TempDir()
+	kubeConfig, err := os.ReadFile("../../test/kubeconfig")
+	assert.Nil(t, err)
+	for _, name := range []string{"staging", "production"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), kubeConfig, 0600))
+	}
+
+	var mu sync.Mutex
+	started := make(map[string]Target)
+	run := func(ctx context.Context, logger *zap.Logger, target Target) error {
+		mu.Lock()
+		started[target.Name] = target
+		mu.Unlock()
+
+		<-ctx.Done()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clusters := supervisor.New(logging.GetLogger(), time.Second, time.Second, true)
+	opts := &options.KubePodTerminatorOptions{KubeConfigDir: dir}
+	assert.Nil(t, Reconcile(ctx, clusters, []string{"../../test/kubeconfig"}, opts, run, logging.GetLogger()))
+	assert.Equal(t, []string{"../../test/kubeconfig", filepath.Join(dir, "production"), filepath.Join(dir, "staging")},
+		clusters.Names())
+
+	// each worker must run its own target
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(started) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	for name, target := range started {
+		assert.Equal(t, name, target.KubeConfigPath)
+	}
+	mu.Unlock()
+
+	// the workers of the removed kubeconfigs are stopped
+	assert.Nil(t, os.Remove(filepath.Join(dir, "staging")))
+	assert.Nil(t, Reconcile(ctx, clusters, []string{"../../test/kubeconfig"}, opts, run, logging.GetLogger()))
+	assert.Equal(t, []string{"../../test/kubeconfig", filepath.Join(dir, "production")}, clusters.Names())
+
+	opts.KubeConfigDir = filepath.Join(dir, "nonexistent")
+	assert.NotNil(t, Reconcile(ctx, clusters, nil, opts, run, logging.GetLogger()))
+
+	cancel()
+	clusters.Wait()
+}
+
+func TestReconcileRejectedKubeConfig(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "broken"), []byte("clusters: [\n"), 0600))
+
+	var mu sync.Mutex
+	var started []string
+	run := func(ctx context.Context, logger *zap.Logger, target Target) error {
+		mu.Lock()
+		started = append(started, target.Name)
+		mu.Unlock()
+
+		return nil
+	}
+
+	// an unreadable kubeconfig fails its own worker without affecting the others
+	clusters := supervisor.New(logging.GetLogger(), time.Second, time.Second, false)
+	opts := &options.KubePodTerminatorOptions{KubeConfigDir: dir, Contexts: "staging,production"}
+	assert.Nil(t, Reconcile(context.Background(), clusters, []string{"../../test/multi_context_kubeconfig"}, opts, run,
+		logging.GetLogger()))
+	clusters.Wait()
+
+	assert.ElementsMatch(t, []string{"staging", "production"}, started)
+	assert.Equal(t, []string{filepath.Join(dir, "broken")}, clusters.Failed())
+}
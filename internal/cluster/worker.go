@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/health"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/k8s"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Worker is the state of a cluster worker which is shared between its runs in the long-running mode
+type Worker struct {
+	logger    *zap.Logger
+	clientSet kubernetes.Interface
+	podLister *k8s.InformerPodLister
+	recorder  record.EventRecorder
+	checker   *health.Checker
+	// limiter throttles the deletions of all the runs, so that each run does not start with a full burst
+	limiter  flowcontrol.RateLimiter
+	cluster  string
+	isLeader func() bool
+	// budget is shared between the runs which are triggered by the informer between two ticks
+	budget k8s.DeletionBudget
+}
+
+// NewWorker returns the Worker of the cluster which runs with the options, it runs as the leader until SetLeader is
+// called and records its ticks on checker
+func NewWorker(logger *zap.Logger, clientSet kubernetes.Interface, podLister *k8s.InformerPodLister,
+	recorder record.EventRecorder, checker *health.Checker, cluster string,
+	opts *options.KubePodTerminatorOptions) *Worker {
+	return &Worker{logger: logger, clientSet: clientSet, podLister: podLister, recorder: recorder, checker: checker,
+		limiter: k8s.NewDeleteRateLimiter(opts), cluster: cluster, isLeader: func() bool { return true }}
+}
+
+// SetLeader sets the function which tells if this replica is the leader of the cluster
+func (w *Worker) SetLeader(isLeader func() bool) {
+	w.isLeader = isLeader
+}
+
+// SetOptions applies the reloaded options on the informer and on the limiter, the limiter is replaced only if its
+// rate is changed
+func (w *Worker) SetOptions(previous, current *options.KubePodTerminatorOptions) {
+	if current.DeleteQPS != previous.DeleteQPS || current.DeleteBurst != previous.DeleteBurst {
+		w.limiter = k8s.NewDeleteRateLimiter(current)
+	}
+
+	w.podLister.SetOptions(current)
+}
+
+// ResetBudget refills the deletion budget of the worker on each tick
+func (w *Worker) ResetBudget() {
+	w.budget.Reset()
+}
+
+// RunIfLeader runs the business logic if this replica is the leader of the cluster and the deletion budget of the
+// ticker interval is not exhausted, and records the tick of the cluster worker in any case
+func (w *Worker) RunIfLeader(ctx context.Context, runOpts *options.KubePodTerminatorOptions) {
+	switch budgetOpts := w.budget.Options(runOpts); {
+	case !w.isLeader():
+		w.logger.Debug("this replica is not the leader, skipping the run", zap.String("cluster", w.cluster))
+	case budgetOpts == nil:
+		w.logger.Info("maximum deletions of the ticker interval are reached, skipping the run until the next tick",
+			zap.String("cluster", w.cluster), zap.Int32("maxDeletionsPerRun", runOpts.MaxDeletionsPerRun))
+	default:
+		// errors of the runs are already logged by Run, the next tick is the retry in the long-running mode
+		report, _ := k8s.Run(ctx, budgetOpts, w.clientSet, w.podLister, w.recorder, w.limiter, w.cluster)
+		w.budget.Spend(report)
+	}
+
+	w.checker.Tick(w.cluster, GetTickerInterval(runOpts), k8s.CheckClientSet(w.clientSet))
+}
+
+// GetTickerInterval returns the interval of the scheduled job of the options
+func GetTickerInterval(opts *options.KubePodTerminatorOptions) time.Duration {
+	return time.Duration(opts.TickerIntervalMinutes) * time.Minute
+}
@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/health"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/k8s"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func getDefaultOpts() *options.KubePodTerminatorOptions {
+	return &options.KubePodTerminatorOptions{
+		Namespace:               "default",
+		TickerIntervalMinutes:   5,
+		GracePeriodSeconds:      30,
+		TerminateEvicted:        true,
+		TerminatingStateMinutes: 30,
+		ListTimeoutSeconds:      60,
+		DryRun:                  options.DryRunNone,
+	}
+}
+
+func TestWorker(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	for _, name := range []string{"varnish-pod-1", "varnish-pod-2", "varnish-pod-3"} {
+		_, err := clientSet.CoreV1().Pods("default").Create(context.Background(), &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     v1.PodStatus{Reason: "Evicted"},
+		}, metav1.CreateOptions{})
+		assert.Nil(t, err)
+	}
+
+	testOpts := getDefaultOpts()
+	testOpts.MaxDeletionsPerRun = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	podLister, err := k8s.NewInformerPodLister(ctx, clientSet, testOpts)
+	assert.Nil(t, err)
+
+	checker := health.NewChecker(time.Minute)
+	w := NewWorker(logging.GetLogger(), clientSet, podLister, record.NewFakeRecorder(100), checker, "minikube",
+		testOpts)
+	getPodCount := func() int {
+		pods, err := clientSet.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+		assert.Nil(t, err)
+
+		return len(pods.Items)
+	}
+
+	// standby replicas do not run, but they still record their ticks
+	w.SetLeader(func() bool { return false })
+	w.RunIfLeader(ctx, testOpts)
+	assert.Equal(t, 3, getPodCount())
+	assert.Nil(t, checker.Live())
+
+	w.SetLeader(func() bool { return true })
+	w.RunIfLeader(ctx, testOpts)
+	assert.Equal(t, 1, getPodCount())
+
+	// the budget of the ticker interval is exhausted until it is reset on the next tick
+	w.RunIfLeader(ctx, testOpts)
+	assert.Equal(t, 1, getPodCount())
+
+	w.ResetBudget()
+	w.RunIfLeader(ctx, testOpts)
+	assert.Equal(t, 0, getPodCount())
+
+	// the limiter is replaced only if its rate is changed
+	assert.Nil(t, w.limiter)
+	current := *testOpts
+	current.DeleteQPS = 1
+	w.SetOptions(testOpts, &current)
+	assert.NotNil(t, w.limiter)
+}
+
+func TestGetTickerInterval(t *testing.T) {
+	assert.Equal(t, 5*time.Minute, GetTickerInterval(getDefaultOpts()))
+}
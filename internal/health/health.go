@@ -0,0 +1,137 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checker tracks the cluster workers of the long-running mode. A worker is stale if its last tick is completed more
+// than its ticker interval plus the staleness window ago, which means it is wedged.
+type Checker struct {
+	mu      sync.RWMutex
+	window  time.Duration
+	workers map[string]*workerStatus
+}
+
+type workerStatus struct {
+	lastTick     time.Time
+	interval     time.Duration
+	ticked       bool
+	clientSetErr error
+}
+
+// NewChecker returns a Checker with the staleness window
+func NewChecker(window time.Duration) *Checker {
+	return &Checker{window: window, workers: make(map[string]*workerStatus)}
+}
+
+// Register adds the worker of the cluster, its staleness is counted from the registration until the first tick
+func (c *Checker) Register(cluster string, interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.workers[cluster] = &workerStatus{lastTick: time.Now(), interval: interval}
+}
+
+// Unregister removes the worker of the cluster, so that a failed worker which is waiting to be restarted is not
+// treated as wedged
+func (c *Checker) Unregister(cluster string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.workers, cluster)
+}
+
+// Tick records a completed tick of the worker of the cluster with its current ticker interval and the result of
+// the clientset health check
+func (c *Checker) Tick(cluster string, interval time.Duration, clientSetErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.workers[cluster] = &workerStatus{lastTick: time.Now(), interval: interval, ticked: true, clientSetErr: clientSetErr}
+}
+
+// Live returns an error if any of the workers is stale
+func (c *Checker) Live() error {
+	return c.check(false, func(cluster string, status *workerStatus) error {
+		return c.checkStaleness(cluster, status)
+	})
+}
+
+// Ready returns an error if there is no worker, or any of the workers is stale, has not completed a tick yet or
+// can not reach its cluster with its clientset
+func (c *Checker) Ready() error {
+	return c.check(true, func(cluster string, status *workerStatus) error {
+		if err := c.checkStaleness(cluster, status); err != nil {
+			return err
+		}
+
+		if !status.ticked {
+			return fmt.Errorf("worker of cluster %s has not completed a tick yet", cluster)
+		}
+
+		if status.clientSetErr != nil {
+			return fmt.Errorf("clientset of cluster %s is unhealthy: %w", cluster, status.clientSetErr)
+		}
+
+		return nil
+	})
+}
+
+func (c *Checker) checkStaleness(cluster string, status *workerStatus) error {
+	if since := time.Since(status.lastTick); since > status.interval+c.window {
+		return fmt.Errorf("worker of cluster %s is stale, last tick is completed %s ago", cluster,
+			since.Round(time.Second))
+	}
+
+	return nil
+}
+
+// check runs checkFunc for the workers in the order of their clusters and returns the first error
+func (c *Checker) check(requireWorker bool, checkFunc func(cluster string, status *workerStatus) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if requireWorker && len(c.workers) == 0 {
+		return errors.New("no cluster worker is registered yet")
+	}
+
+	clusters := make([]string, 0, len(c.workers))
+	for cluster := range c.workers {
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Strings(clusters)
+	for _, cluster := range clusters {
+		if err := checkFunc(cluster, c.workers[cluster]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler returns the http.Handler which serves /healthz with Live and /readyz with Ready. Failing checks are
+// responded with 503 and the error.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", probeHandler(c.Live))
+	mux.HandleFunc("/readyz", probeHandler(c.Ready))
+
+	return mux
+}
+
+func probeHandler(probe func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := probe(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(time.Minute)
+	assert.Nil(t, checker.Live())
+	assert.NotNil(t, checker.Ready())
+
+	checker.Register("https://127.0.0.1:6443", time.Minute)
+	assert.Nil(t, checker.Live())
+	assert.NotNil(t, checker.Ready())
+
+	checker.Tick("https://127.0.0.1:6443", time.Minute, nil)
+	assert.Nil(t, checker.Live())
+	assert.Nil(t, checker.Ready())
+
+	checker.Tick("https://127.0.0.1:6443", time.Minute, errors.New("connection refused"))
+	assert.Nil(t, checker.Live())
+	assert.NotNil(t, checker.Ready())
+
+	checker.Tick("https://127.0.0.1:6443", time.Minute, nil)
+	checker.workers["https://127.0.0.1:6443"].lastTick = time.Now().Add(-3 * time.Minute)
+	assert.NotNil(t, checker.Live())
+	assert.NotNil(t, checker.Ready())
+
+	checker.Unregister("https://127.0.0.1:6443")
+	assert.Nil(t, checker.Live())
+	assert.NotNil(t, checker.Ready())
+}
+
+func TestHandler(t *testing.T) {
+	cases := []struct {
+		caseName, path string
+		ticked         bool
+		expectedStatus int
+	}{
+		{"case1", "/healthz", false, http.StatusOK},
+		{"case2", "/readyz", false, http.StatusServiceUnavailable},
+		{"case3", "/readyz", true, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			checker := NewChecker(time.Minute)
+			checker.Register("https://127.0.0.1:6443", time.Minute)
+			if tc.ticked {
+				checker.Tick("https://127.0.0.1:6443", time.Minute, nil)
+			}
+
+			recorder := httptest.NewRecorder()
+			checker.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
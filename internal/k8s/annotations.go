@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// annotationPrefix is the prefix of the annotations which are read by kube-pod-terminator
+	annotationPrefix = "kube-pod-terminator/"
+	// skipAnnotation makes every detector ignore the pods when it is "true" on the pods, their owners or namespaces
+	skipAnnotation = annotationPrefix + "skip"
+	// maxOwnerDepth is the number of the controller owners which are looked up for a pod, e.g. ReplicaSet and
+	// Deployment
+	maxOwnerDepth = 2
+)
+
+// thresholdAnnotations maps the annotations which override the thresholds to the fields of the options
+var thresholdAnnotations = map[string]func(opts *options.KubePodTerminatorOptions) *int32{
+	annotationPrefix + "terminating-minutes": func(opts *options.KubePodTerminatorOptions) *int32 {
+		return &opts.TerminatingStateMinutes
+	},
+	annotationPrefix + "evicted-minutes": func(opts *options.KubePodTerminatorOptions) *int32 {
+		return &opts.EvictedStateMinutes
+	},
+	annotationPrefix + "crashloop-minutes": func(opts *options.KubePodTerminatorOptions) *int32 {
+		return &opts.CrashLoopStateMinutes
+	},
+	annotationPrefix + "image-pull-error-minutes": func(opts *options.KubePodTerminatorOptions) *int32 {
+		return &opts.ImagePullErrorStateMinutes
+	},
+	annotationPrefix + "succeeded-retention-minutes": func(opts *options.KubePodTerminatorOptions) *int32 {
+		return &opts.SucceededRetentionMinutes
+	},
+	annotationPrefix + "failed-retention-minutes": func(opts *options.KubePodTerminatorOptions) *int32 {
+		return &opts.FailedRetentionMinutes
+	},
+}
+
+// annotationFilter applies the annotations of the namespaces, the owners and the pods themselves on the candidates.
+// Namespaces and owners are fetched once per run.
+type annotationFilter struct {
+	ctx       context.Context
+	clientSet kubernetes.Interface
+	cache     map[string]metav1.Object
+}
+
+func newAnnotationFilter(ctx context.Context, clientSet kubernetes.Interface) *annotationFilter {
+	return &annotationFilter{ctx: ctx, clientSet: clientSet, cache: make(map[string]metav1.Object)}
+}
+
+// filter returns the candidates which are still matched by the detector after the annotations of their namespaces,
+// controller owners and themselves are applied. The candidates which are not matched anymore with the overridden
+// thresholds are removed from matched, so that other detectors can still evaluate them. Skipped candidates are kept
+// in matched.
+func (f *annotationFilter) filter(d registeredDetector, candidates []candidate, matched map[string]struct{},
+	logger *zap.Logger) []candidate {
+	result := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		podLogger := logger.With(zap.String("name", c.pod.Name), zap.String("namespace", c.pod.Namespace),
+			zap.String("state", d.name))
+		annotated, err := f.apply(c)
+		if err != nil {
+			podLogger.Warn("an error occurred while getting annotations, skipping pod", zap.Error(err))
+			continue
+		}
+
+		if annotated == nil {
+			podLogger.Info("pod is skipped by annotation", zap.String("annotation", skipAnnotation))
+			continue
+		}
+
+		if annotated != c.opts {
+			detector := d.factory(annotated)
+			if detector == nil || !detector.Match(c.pod) {
+				podLogger.Info("pod is not matched with the thresholds which are overridden by annotations")
+				delete(matched, c.pod.Namespace+"/"+c.pod.Name)
+				continue
+			}
+
+			c.detector, c.opts = detector, annotated
+		}
+
+		// the namespace is already cached by apply, it is kept for the events of the candidate
+		namespace, _ := f.get("Namespace", "", c.pod.Namespace)
+		c.namespace, _ = namespace.(*v1.Namespace)
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// override returns the options which are overridden by the threshold annotations of the namespace of the pod and of
+// the pod itself, so that they can shorten the thresholds before the pod is matched. The controller owners are not
+// read here, since that would need a request per owner for each pod. The options are returned as is if the
+// annotations cannot be read or are invalid, filter skips such pods once they are matched.
+func (f *annotationFilter) override(pod v1.Pod, opts *options.KubePodTerminatorOptions) *options.KubePodTerminatorOptions {
+	objects := []metav1.Object{&pod}
+	namespace, err := f.get("Namespace", "", pod.Namespace)
+	if err != nil {
+		return opts
+	}
+
+	if namespace != nil {
+		objects = append([]metav1.Object{namespace}, objects...)
+	}
+
+	overridden, err := applyAnnotations(opts, objects)
+	if err != nil || overridden == nil {
+		return opts
+	}
+
+	return overridden
+}
+
+// apply returns the options of the candidate which are overridden by the threshold annotations, the more specific
+// object wins. Returns nil if the candidate is skipped by annotation, and the options of the candidate as is if
+// there is no threshold annotation.
+func (f *annotationFilter) apply(c candidate) (*options.KubePodTerminatorOptions, error) {
+	objects, err := f.getAnnotatedObjects(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyAnnotations(c.opts, objects)
+}
+
+// applyAnnotations returns the options which are overridden by the threshold annotations of the objects, which are
+// ordered from the least specific to the most specific one. Returns nil if any of the objects has the skip
+// annotation, and opts as is if there is no threshold annotation.
+func applyAnnotations(opts *options.KubePodTerminatorOptions, objects []metav1.Object) (*options.KubePodTerminatorOptions,
+	error) {
+	result := opts
+	for _, object := range objects {
+		annotations := object.GetAnnotations()
+		if annotations[skipAnnotation] == "true" {
+			return nil, nil
+		}
+
+		for annotation, field := range thresholdAnnotations {
+			value, ok := annotations[annotation]
+			if !ok {
+				continue
+			}
+
+			minutes, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of annotation %s on %s: %w", value, annotation,
+					object.GetName(), err)
+			}
+
+			if result == opts {
+				copied := *opts
+				result = &copied
+			}
+
+			*field(result) = int32(minutes)
+		}
+	}
+
+	return result, nil
+}
+
+// getAnnotatedObjects returns the namespace, the controller owners and the pod itself, from the least specific to
+// the most specific one
+func (f *annotationFilter) getAnnotatedObjects(c candidate) ([]metav1.Object, error) {
+	objects := []metav1.Object{&c.pod}
+	var current metav1.Object = &c.pod
+	for i := 0; i < maxOwnerDepth; i++ {
+		owner := metav1.GetControllerOf(current)
+		if owner == nil {
+			break
+		}
+
+		object, err := f.get(owner.Kind, c.pod.Namespace, owner.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if object == nil {
+			break
+		}
+
+		objects = append([]metav1.Object{object}, objects...)
+		current = object
+	}
+
+	namespace, err := f.get("Namespace", "", c.pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace != nil {
+		objects = append([]metav1.Object{namespace}, objects...)
+	}
+
+	return objects, nil
+}
+
+// get returns the object from the cache or the kube-apiserver. It returns nil for the unknown kinds and for the
+// objects which are not found, since they have no annotations to apply. Any other error, e.g. forbidden, is returned,
+// so that the pod is skipped instead of terminating it without knowing whether it is opted out.
+func (f *annotationFilter) get(kind, namespace, name string) (metav1.Object, error) {
+	key := kind + "/" + namespace + "/" + name
+	if object, ok := f.cache[key]; ok {
+		return object, nil
+	}
+
+	object, err := f.fetch(kind, namespace, name)
+	if apierrors.IsNotFound(err) {
+		object, err = nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.cache[key] = object
+
+	return object, nil
+}
+
+func (f *annotationFilter) fetch(kind, namespace, name string) (metav1.Object, error) {
+	getOptions := metav1.GetOptions{}
+	switch kind {
+	case "Namespace":
+		return f.clientSet.CoreV1().Namespaces().Get(f.ctx, name, getOptions)
+	case "ReplicationController":
+		return f.clientSet.CoreV1().ReplicationControllers(namespace).Get(f.ctx, name, getOptions)
+	case "ReplicaSet":
+		return f.clientSet.AppsV1().ReplicaSets(namespace).Get(f.ctx, name, getOptions)
+	case "Deployment":
+		return f.clientSet.AppsV1().Deployments(namespace).Get(f.ctx, name, getOptions)
+	case "StatefulSet":
+		return f.clientSet.AppsV1().StatefulSets(namespace).Get(f.ctx, name, getOptions)
+	case "DaemonSet":
+		return f.clientSet.AppsV1().DaemonSets(namespace).Get(f.ctx, name, getOptions)
+	case "Job":
+		return f.clientSet.BatchV1().Jobs(namespace).Get(f.ctx, name, getOptions)
+	case "CronJob":
+		return f.clientSet.BatchV1().CronJobs(namespace).Get(f.ctx, name, getOptions)
+	default:
+		return nil, nil
+	}
+}
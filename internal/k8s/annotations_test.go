@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAnnotationFilter(t *testing.T) {
+	isController := true
+	cases := []struct {
+		caseName                                    string
+		namespaceAnnotations, deploymentAnnotations map[string]string
+		podAnnotations                              map[string]string
+		withOwner                                   bool
+		expectedCandidates, expectedMatched         int
+		expectedTerminatingMinutes                  int32
+	}{
+		{"case1", nil, nil, nil, true, 1, 1, 30},
+		{"case2", nil, nil, map[string]string{skipAnnotation: "true"}, true, 0, 1, 0},
+		{"case3", nil, map[string]string{skipAnnotation: "true"}, nil, true, 0, 1, 0},
+		{"case4", map[string]string{skipAnnotation: "true"}, nil, nil, false, 0, 1, 0},
+		{"case5", nil, map[string]string{skipAnnotation: "false"}, nil, true, 1, 1, 30},
+		{"case6", nil, map[string]string{annotationPrefix + "terminating-minutes": "120"}, nil, true, 0, 0, 0},
+		{"case7", nil, map[string]string{annotationPrefix + "terminating-minutes": "120"},
+			map[string]string{annotationPrefix + "terminating-minutes": "45"}, true, 1, 1, 45},
+		{"case8", nil, nil, map[string]string{annotationPrefix + "terminating-minutes": "forever"}, true, 0, 1, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			ctx := context.Background()
+			_, err := api.ClientSet.CoreV1().Namespaces().Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name: "default", Annotations: tc.namespaceAnnotations}}, metav1.CreateOptions{})
+			assert.Nil(t, err)
+
+			_, err = api.ClientSet.AppsV1().Deployments("default").Create(ctx, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "varnish", Namespace: "default",
+					Annotations: tc.deploymentAnnotations}}, metav1.CreateOptions{})
+			assert.Nil(t, err)
+
+			_, err = api.ClientSet.AppsV1().ReplicaSets("default").Create(ctx, &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "varnish-7d4b9c", Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment",
+						Name: "varnish", Controller: &isController}}}}, metav1.CreateOptions{})
+			assert.Nil(t, err)
+
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "varnish-7d4b9c-x2x8p", Namespace: "default",
+				Annotations:       tc.podAnnotations,
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-time.Hour)}}}
+			if tc.withOwner {
+				pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet",
+					Name: "varnish-7d4b9c", Controller: &isController}}
+			}
+
+			testOpts := getDefaultOpts()
+			matched := map[string]struct{}{"default/varnish-7d4b9c-x2x8p": {}}
+			candidates := []candidate{{pod: pod, detector: newTerminatingDetector(testOpts), opts: testOpts}}
+
+			var terminating registeredDetector
+			for _, d := range getRegisteredDetectors() {
+				if d.name == "terminating" {
+					terminating = d
+				}
+			}
+
+			candidates = newAnnotationFilter(ctx, api.ClientSet).filter(terminating, candidates, matched,
+				logging.GetLogger())
+			assert.Len(t, candidates, tc.expectedCandidates)
+			assert.Len(t, matched, tc.expectedMatched)
+			if len(candidates) > 0 {
+				assert.Equal(t, tc.expectedTerminatingMinutes, candidates[0].opts.TerminatingStateMinutes)
+				assert.Equal(t, "default", candidates[0].namespace.Name)
+			}
+
+			assert.Equal(t, int32(30), testOpts.TerminatingStateMinutes)
+		})
+	}
+}
+
+func TestAnnotationOverride(t *testing.T) {
+	isController := true
+	terminatingMinutes := annotationPrefix + "terminating-minutes"
+	cases := []struct {
+		caseName                                    string
+		namespaceAnnotations, deploymentAnnotations map[string]string
+		podAnnotations                              map[string]string
+		expectedCandidates                          int
+		expectedTerminatingMinutes                  int32
+	}{
+		{"case1", nil, nil, nil, 0, 0},
+		{"case2", nil, nil, map[string]string{terminatingMinutes: "15"}, 1, 15},
+		{"case3", map[string]string{terminatingMinutes: "15"}, nil, nil, 1, 15},
+		{"case4", map[string]string{terminatingMinutes: "15"}, nil, map[string]string{terminatingMinutes: "45"}, 0, 0},
+		{"case5", map[string]string{terminatingMinutes: "15"}, map[string]string{terminatingMinutes: "45"}, nil, 0, 0},
+		{"case6", nil, map[string]string{terminatingMinutes: "15"}, nil, 0, 0},
+		{"case7", nil, nil, map[string]string{terminatingMinutes: "forever"}, 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			ctx := context.Background()
+			_, err := api.ClientSet.CoreV1().Namespaces().Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name: "default", Annotations: tc.namespaceAnnotations}}, metav1.CreateOptions{})
+			assert.Nil(t, err)
+
+			_, err = api.ClientSet.AppsV1().Deployments("default").Create(ctx, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "varnish", Namespace: "default",
+					Annotations: tc.deploymentAnnotations}}, metav1.CreateOptions{})
+			assert.Nil(t, err)
+
+			// the pod is terminating for less than the configured threshold of 30 minutes
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "varnish-pod-1", Namespace: "default",
+				Annotations:       tc.podAnnotations,
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-20 * time.Minute)},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment",
+					Name: "varnish", Controller: &isController}}}}
+
+			var terminating registeredDetector
+			for _, d := range getRegisteredDetectors() {
+				if d.name == "terminating" {
+					terminating = d
+				}
+			}
+
+			filter := newAnnotationFilter(ctx, api.ClientSet)
+			matched := make(map[string]struct{})
+			candidates := newDetectorResolver(getDefaultOpts()).detectPods(terminating, []v1.Pod{pod}, matched,
+				filter.override)
+			candidates = filter.filter(terminating, candidates, matched, logging.GetLogger())
+			assert.Len(t, candidates, tc.expectedCandidates)
+			assert.Len(t, matched, tc.expectedCandidates)
+			if len(candidates) > 0 {
+				assert.Equal(t, tc.expectedTerminatingMinutes, candidates[0].opts.TerminatingStateMinutes)
+			}
+		})
+	}
+}
+
+func TestAnnotationFilterForbidden(t *testing.T) {
+	api := getFakeAPI()
+	api.ClientSet.(*fake.Clientset).PrependReactor("get", "namespaces",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(v1.Resource("namespaces"), "default", errors.New("denied"))
+		})
+
+	testOpts := getDefaultOpts()
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "varnish-pod-1", Namespace: "default",
+		DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-time.Hour)}}}
+	matched := map[string]struct{}{"default/varnish-pod-1": {}}
+	candidates := []candidate{{pod: pod, detector: newTerminatingDetector(testOpts), opts: testOpts}}
+
+	// the pod may be opted out on its namespace, so it is skipped if the namespace can not be read
+	candidates = newAnnotationFilter(context.Background(), api.ClientSet).filter(getRegisteredDetectors()[0],
+		candidates, matched, logging.GetLogger())
+	assert.Len(t, candidates, 0)
+	assert.Len(t, matched, 1)
+}
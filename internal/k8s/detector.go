@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Detector selects the pods which are in an unwanted state and should be terminated
+type Detector interface {
+	// Name is the unique name of the detector, it is also used as the state of the matched pods
+	Name() string
+	// Reason is the human-readable explanation of why the matched pods are terminated
+	Reason() string
+	// Match returns true if the pod is in the state which the detector looks for
+	Match(pod v1.Pod) bool
+}
+
+// StateAgeDetector is an optional interface which a Detector can implement to tell since when a matched pod is in
+// the state which the detector looks for. It is used for the age in state of the run report, the age of the pod is
+// used for the other detectors.
+type StateAgeDetector interface {
+	Detector
+	// StateSince returns the time when the pod entered the state
+	StateSince(pod v1.Pod) time.Time
+}
+
+// DetectorFactory builds a Detector with the given options, returns nil if the detector is disabled by the options
+type DetectorFactory func(opts *options.KubePodTerminatorOptions) Detector
+
+type registeredDetector struct {
+	name    string
+	factory DetectorFactory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []registeredDetector
+)
+
+// RegisterDetector makes a detector available to Run with the given name. Detectors are evaluated in the order
+// they are registered. It panics if a detector with the same name is already registered.
+func RegisterDetector(name string, factory DetectorFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("k8s: RegisterDetector factory is nil")
+	}
+
+	for _, d := range registry {
+		if d.name == name {
+			panic(fmt.Sprintf("k8s: RegisterDetector called twice for detector %s", name))
+		}
+	}
+
+	registry = append(registry, registeredDetector{name: name, factory: factory})
+}
+
+// getRegisteredDetectors returns a copy of the registry, so that it can be iterated without holding the lock
+func getRegisteredDetectors() []registeredDetector {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]registeredDetector(nil), registry...)
+}
+
+// getStateAge returns the duration since the pod is in the state of the detector
+func getStateAge(d Detector, pod v1.Pod) time.Duration {
+	if stateAgeDetector, ok := d.(StateAgeDetector); ok {
+		return time.Since(stateAgeDetector.StateSince(pod))
+	}
+
+	return getPodAge(pod)
+}
@@ -0,0 +1,271 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeDetector struct {
+	name string
+}
+
+func (d *fakeDetector) Name() string {
+	return d.name
+}
+
+func (d *fakeDetector) Reason() string {
+	return "pod is matched by the fake detector"
+}
+
+func (d *fakeDetector) Match(pod v1.Pod) bool {
+	return pod.Labels["fake"] == "true"
+}
+
+func TestRegisterDetector(t *testing.T) {
+	RegisterDetector("fake", func(opts *options.KubePodTerminatorOptions) Detector {
+		return &fakeDetector{name: "fake"}
+	})
+	defer func() {
+		registryMu.Lock()
+		registry = registry[:len(registry)-1]
+		registryMu.Unlock()
+	}()
+
+	detectors := getRegisteredDetectors()
+	assert.Equal(t, "fake", detectors[len(detectors)-1].name)
+	assert.Equal(t, "fake", newDetectorResolver(getDefaultOpts()).enabled(detectors[len(detectors)-1]).Name())
+
+	assert.Panics(t, func() {
+		RegisterDetector("fake", func(opts *options.KubePodTerminatorOptions) Detector {
+			return nil
+		})
+	})
+	assert.Panics(t, func() {
+		RegisterDetector("nil-factory", nil)
+	})
+}
+
+func TestDetectorResolverEnabled(t *testing.T) {
+	terminateEvicted := true
+
+	cases := []struct {
+		caseName         string
+		terminateEvicted bool
+		policies         []options.Policy
+		expected         []string
+	}{
+		{"case1", true, nil, []string{"terminating", "evicted"}},
+		{"case2", false, nil, []string{"terminating"}},
+		{"case3", false, []options.Policy{{Namespaces: []string{"prod"}, TerminateEvicted: &terminateEvicted}},
+			[]string{"terminating", "evicted"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			testOpts := getDefaultOpts()
+			testOpts.TerminateEvicted = tc.terminateEvicted
+			testOpts.Policies = tc.policies
+			resolver := newDetectorResolver(testOpts)
+
+			var names []string
+			for _, d := range getRegisteredDetectors() {
+				if detector := resolver.enabled(d); detector != nil {
+					names = append(names, detector.Name())
+				}
+			}
+
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestTerminatingDetector(t *testing.T) {
+	detector := newTerminatingDetector(getDefaultOpts())
+
+	cases := []struct {
+		caseName          string
+		deletionTimestamp *metav1.Time
+		expected          bool
+	}{
+		{"case1", nil, false},
+		{"case2", &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}, false},
+		{"case3", &metav1.Time{Time: time.Now().Add(-60 * time.Minute)}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: tc.deletionTimestamp}}
+			assert.Equal(t, tc.expected, detector.Match(pod))
+		})
+	}
+}
+
+func TestEvictedDetector(t *testing.T) {
+	detector := newEvictedDetector(getDefaultOpts())
+	assert.True(t, detector.Match(v1.Pod{Status: v1.PodStatus{Reason: "Evicted"}}))
+	assert.False(t, detector.Match(v1.Pod{}))
+}
+
+func TestDetectPods(t *testing.T) {
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default", Labels: map[string]string{"fake": "true"}},
+			Status: v1.PodStatus{Reason: "Evicted"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default", Labels: map[string]string{"fake": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-3", Namespace: "default"}},
+	}
+
+	resolver := newDetectorResolver(getDefaultOpts())
+	matched := make(map[string]struct{})
+	evicted := resolver.detectPods(registeredDetector{name: "evicted", factory: newEvictedDetector}, pods, matched, nil)
+	assert.Len(t, evicted, 1)
+
+	// pod-1 is already matched by the evicted detector, so it must not be matched twice
+	fake := resolver.detectPods(registeredDetector{name: "fake", factory: func(opts *options.KubePodTerminatorOptions) Detector {
+		return &fakeDetector{name: "fake"}
+	}}, pods, matched, nil)
+	assert.Len(t, fake, 1)
+	assert.Equal(t, "pod-2", fake[0].pod.Name)
+}
+
+func getWaitingPod(reason string, restartCount int32, notReadySince time.Time) v1.Pod {
+	return v1.Pod{
+		Status: v1.PodStatus{
+			Conditions: []v1.PodCondition{
+				{
+					Type:               v1.ContainersReady,
+					Status:             v1.ConditionFalse,
+					LastTransitionTime: metav1.Time{Time: notReadySince},
+				},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:         "varnish",
+					RestartCount: restartCount,
+					State:        v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason}},
+				},
+			},
+		},
+	}
+}
+
+func TestCrashLoopDetector(t *testing.T) {
+	testOpts := getDefaultOpts()
+	assert.Nil(t, newCrashLoopDetector(testOpts))
+
+	cases := []struct {
+		caseName     string
+		restartCount int32
+		pod          v1.Pod
+		expected     bool
+	}{
+		{"case1", 0, v1.Pod{}, false},
+		{"case2", 0, getWaitingPod("CrashLoopBackOff", 3, time.Now().Add(-10*time.Minute)), false},
+		{"case3", 0, getWaitingPod("CrashLoopBackOff", 3, time.Now().Add(-90*time.Minute)), true},
+		{"case4", 5, getWaitingPod("CrashLoopBackOff", 5, time.Now().Add(-10*time.Minute)), true},
+		{"case5", 5, getWaitingPod("ContainerCreating", 5, time.Now().Add(-90*time.Minute)), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			testOpts := getDefaultOpts()
+			testOpts.TerminateCrashLoop = true
+			testOpts.CrashLoopRestartCount = tc.restartCount
+
+			detector := newCrashLoopDetector(testOpts)
+			assert.NotNil(t, detector)
+			assert.Equal(t, tc.expected, detector.Match(tc.pod))
+		})
+	}
+}
+
+func TestImagePullDetector(t *testing.T) {
+	testOpts := getDefaultOpts()
+	assert.Nil(t, newImagePullDetector(testOpts))
+
+	testOpts.TerminateImagePullErrors = true
+	detector := newImagePullDetector(testOpts)
+	assert.NotNil(t, detector)
+
+	cases := []struct {
+		caseName string
+		pod      v1.Pod
+		expected bool
+	}{
+		{"case1", v1.Pod{}, false},
+		{"case2", getWaitingPod("ImagePullBackOff", 0, time.Now().Add(-10*time.Minute)), false},
+		{"case3", getWaitingPod("ImagePullBackOff", 0, time.Now().Add(-60*time.Minute)), true},
+		{"case4", getWaitingPod("ErrImagePull", 0, time.Now().Add(-60*time.Minute)), true},
+		{"case5", getWaitingPod("InvalidImageName", 0, time.Now().Add(-60*time.Minute)), true},
+		{"case6", getWaitingPod("CrashLoopBackOff", 0, time.Now().Add(-60*time.Minute)), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, detector.Match(tc.pod))
+		})
+	}
+}
+
+func getCompletedPod(phase v1.PodPhase, finishedAt time.Time) v1.Pod {
+	return v1.Pod{
+		Status: v1.PodStatus{
+			Phase: phase,
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name: "varnish",
+					State: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: finishedAt}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPhaseDetectors(t *testing.T) {
+	testOpts := getDefaultOpts()
+	assert.Nil(t, newSucceededDetector(testOpts))
+	assert.Nil(t, newFailedDetector(testOpts))
+
+	testOpts.SucceededRetentionMinutes = 60
+	testOpts.FailedRetentionMinutes = 120
+	succeeded := newSucceededDetector(testOpts)
+	failed := newFailedDetector(testOpts)
+	assert.Equal(t, "succeeded", succeeded.Name())
+	assert.Equal(t, "failed", failed.Name())
+
+	cases := []struct {
+		caseName            string
+		pod                 v1.Pod
+		succeeded, failedOK bool
+	}{
+		{"case1", getCompletedPod(v1.PodSucceeded, time.Now().Add(-10*time.Minute)), false, false},
+		{"case2", getCompletedPod(v1.PodSucceeded, time.Now().Add(-90*time.Minute)), true, false},
+		{"case3", getCompletedPod(v1.PodFailed, time.Now().Add(-90*time.Minute)), false, false},
+		{"case4", getCompletedPod(v1.PodFailed, time.Now().Add(-150*time.Minute)), false, true},
+		{"case5", getCompletedPod(v1.PodRunning, time.Now().Add(-150*time.Minute)), false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			assert.Equal(t, tc.succeeded, succeeded.Match(tc.pod))
+			assert.Equal(t, tc.failedOK, failed.Match(tc.pod))
+		})
+	}
+}
+
+func TestGetStateAge(t *testing.T) {
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		CreationTimestamp: metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+		DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-time.Hour)},
+	}}
+
+	// the age of the pod is used for the detectors which do not implement StateAgeDetector
+	assert.InDelta(t, time.Hour.Seconds(), getStateAge(newTerminatingDetector(getDefaultOpts()), pod).Seconds(), 5)
+	assert.InDelta(t, (2 * time.Hour).Seconds(), getStateAge(&fakeDetector{name: "fake"}, pod).Seconds(), 5)
+}
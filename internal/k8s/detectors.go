@@ -0,0 +1,258 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	v1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	RegisterDetector("terminating", newTerminatingDetector)
+	RegisterDetector("evicted", newEvictedDetector)
+	RegisterDetector("crashloop", newCrashLoopDetector)
+	RegisterDetector("imagepull", newImagePullDetector)
+	RegisterDetector("succeeded", newSucceededDetector)
+	RegisterDetector("failed", newFailedDetector)
+}
+
+// terminatingDetector matches the pods which are stuck in terminating state more than the specified minutes
+type terminatingDetector struct {
+	terminatingStateMinutes int32
+}
+
+func newTerminatingDetector(opts *options.KubePodTerminatorOptions) Detector {
+	return &terminatingDetector{terminatingStateMinutes: opts.TerminatingStateMinutes}
+}
+
+func (d *terminatingDetector) Name() string {
+	return "terminating"
+}
+
+func (d *terminatingDetector) Reason() string {
+	return "pod is stuck in terminating state"
+}
+
+func (d *terminatingDetector) Match(pod v1.Pod) bool {
+	deletionTimestamp := pod.ObjectMeta.DeletionTimestamp
+	return deletionTimestamp != nil && deletionTimestamp.Add(time.Duration(d.terminatingStateMinutes)*time.Minute).Before(time.Now())
+}
+
+func (d *terminatingDetector) StateSince(pod v1.Pod) time.Time {
+	if pod.DeletionTimestamp == nil {
+		return pod.CreationTimestamp.Time
+	}
+
+	return pod.DeletionTimestamp.Time
+}
+
+// evictedDetector matches the pods which are evicted by the kubelet more than the specified minutes ago
+type evictedDetector struct {
+	evictedStateMinutes int32
+}
+
+func newEvictedDetector(opts *options.KubePodTerminatorOptions) Detector {
+	if !opts.TerminateEvicted {
+		return nil
+	}
+
+	return &evictedDetector{evictedStateMinutes: opts.EvictedStateMinutes}
+}
+
+func (d *evictedDetector) Name() string {
+	return "evicted"
+}
+
+func (d *evictedDetector) Reason() string {
+	return "pod is evicted"
+}
+
+func (d *evictedDetector) Match(pod v1.Pod) bool {
+	return pod.Status.Reason == "Evicted" &&
+		getFinishedAt(pod).Add(time.Duration(d.evictedStateMinutes)*time.Minute).Before(time.Now())
+}
+
+func (d *evictedDetector) StateSince(pod v1.Pod) time.Time {
+	return getFinishedAt(pod)
+}
+
+// crashLoopDetector matches the pods which have a container in CrashLoopBackOff for more than the specified minutes
+// or with more restarts than the specified count
+type crashLoopDetector struct {
+	crashLoopStateMinutes int32
+	crashLoopRestartCount int32
+}
+
+func newCrashLoopDetector(opts *options.KubePodTerminatorOptions) Detector {
+	if !opts.TerminateCrashLoop {
+		return nil
+	}
+
+	return &crashLoopDetector{
+		crashLoopStateMinutes: opts.CrashLoopStateMinutes,
+		crashLoopRestartCount: opts.CrashLoopRestartCount,
+	}
+}
+
+func (d *crashLoopDetector) Name() string {
+	return "crashloop"
+}
+
+func (d *crashLoopDetector) Reason() string {
+	return "pod is stuck in CrashLoopBackOff"
+}
+
+func (d *crashLoopDetector) Match(pod v1.Pod) bool {
+	status := getWaitingContainerStatus(pod, "CrashLoopBackOff")
+	if status == nil {
+		return false
+	}
+
+	if d.crashLoopRestartCount > 0 && status.RestartCount >= d.crashLoopRestartCount {
+		return true
+	}
+
+	return getNotReadySince(pod).Add(time.Duration(d.crashLoopStateMinutes) * time.Minute).Before(time.Now())
+}
+
+func (d *crashLoopDetector) StateSince(pod v1.Pod) time.Time {
+	return getNotReadySince(pod)
+}
+
+// imagePullDetector matches the pods which have a container that can not pull its image for more than the specified
+// minutes
+type imagePullDetector struct {
+	imagePullErrorStateMinutes int32
+}
+
+func newImagePullDetector(opts *options.KubePodTerminatorOptions) Detector {
+	if !opts.TerminateImagePullErrors {
+		return nil
+	}
+
+	return &imagePullDetector{imagePullErrorStateMinutes: opts.ImagePullErrorStateMinutes}
+}
+
+func (d *imagePullDetector) Name() string {
+	return "imagepull"
+}
+
+func (d *imagePullDetector) Reason() string {
+	return "pod is stuck in ImagePullBackOff, ErrImagePull or InvalidImageName"
+}
+
+func (d *imagePullDetector) Match(pod v1.Pod) bool {
+	if getWaitingContainerStatus(pod, "ImagePullBackOff", "ErrImagePull", "InvalidImageName") == nil {
+		return false
+	}
+
+	return getNotReadySince(pod).Add(time.Duration(d.imagePullErrorStateMinutes) * time.Minute).Before(time.Now())
+}
+
+func (d *imagePullDetector) StateSince(pod v1.Pod) time.Time {
+	return getNotReadySince(pod)
+}
+
+// phaseDetector matches the pods which are completed in the specified phase for more than the retention minutes
+type phaseDetector struct {
+	phase            v1.PodPhase
+	retentionMinutes int32
+}
+
+func newSucceededDetector(opts *options.KubePodTerminatorOptions) Detector {
+	if opts.SucceededRetentionMinutes <= 0 {
+		return nil
+	}
+
+	return &phaseDetector{phase: v1.PodSucceeded, retentionMinutes: opts.SucceededRetentionMinutes}
+}
+
+func newFailedDetector(opts *options.KubePodTerminatorOptions) Detector {
+	if opts.FailedRetentionMinutes <= 0 {
+		return nil
+	}
+
+	return &phaseDetector{phase: v1.PodFailed, retentionMinutes: opts.FailedRetentionMinutes}
+}
+
+func (d *phaseDetector) Name() string {
+	return strings.ToLower(string(d.phase))
+}
+
+func (d *phaseDetector) Reason() string {
+	return fmt.Sprintf("pod is in %s phase for more than the retention window", d.phase)
+}
+
+func (d *phaseDetector) Match(pod v1.Pod) bool {
+	if pod.Status.Phase != d.phase {
+		return false
+	}
+
+	return getFinishedAt(pod).Add(time.Duration(d.retentionMinutes) * time.Minute).Before(time.Now())
+}
+
+func (d *phaseDetector) StateSince(pod v1.Pod) time.Time {
+	return getFinishedAt(pod)
+}
+
+// getWaitingContainerStatus returns the status of the first init or app container of the pod which is waiting
+// with one of the given reasons, returns nil if there is no such container
+func getWaitingContainerStatus(pod v1.Pod, reasons ...string) *v1.ContainerStatus {
+	statuses := append(append([]v1.ContainerStatus(nil), pod.Status.InitContainerStatuses...),
+		pod.Status.ContainerStatuses...)
+	for i, status := range statuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+
+		for _, reason := range reasons {
+			if status.State.Waiting.Reason == reason {
+				return &statuses[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// getNotReadySince returns the time since the containers of the pod are not ready. Kubernetes does not record
+// since when a container is waiting, so the last transition of the ContainersReady condition is used, falling back
+// to the start time and then to the creation time of the pod.
+func getNotReadySince(pod v1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.ContainersReady && condition.Status != v1.ConditionTrue &&
+			!condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time
+		}
+	}
+
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time
+	}
+
+	return pod.CreationTimestamp.Time
+}
+
+// getFinishedAt returns the time when the last container of the pod is terminated, falling back to the start time
+// and then to the creation time of the pod
+func getFinishedAt(pod v1.Pod) time.Time {
+	var finishedAt time.Time
+	for _, status := range append(append([]v1.ContainerStatus(nil), pod.Status.InitContainerStatuses...),
+		pod.Status.ContainerStatuses...) {
+		if status.State.Terminated != nil && status.State.Terminated.FinishedAt.After(finishedAt) {
+			finishedAt = status.State.Terminated.FinishedAt.Time
+		}
+	}
+
+	if !finishedAt.IsZero() {
+		return finishedAt
+	}
+
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time
+	}
+
+	return pod.CreationTimestamp.Time
+}
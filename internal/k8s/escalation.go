@@ -0,0 +1,203 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// removeFinalizersPatch is the merge patch which clears metadata.finalizers of a pod
+	removeFinalizersPatch = `{"metadata":{"finalizers":null}}`
+
+	stepDelete           = "delete"
+	stepEvict            = "evict"
+	stepForceDelete      = "force-delete"
+	stepRemoveFinalizers = "remove-finalizers"
+)
+
+var (
+	errPodStillExists   = errors.New("pod still exists after all of the enabled escalation steps")
+	errEvictionDeferred = errors.New("eviction is rejected to respect a PodDisruptionBudget, deferring to the next run")
+)
+
+// deletePod terminates the pod with an escalation ladder, each step runs only if the pod still exists after the
+// previous one:
+//  1. a normal deletion with the grace period, or an eviction for the live pods if UseEviction is enabled. It is
+//     skipped if the pod is already being deleted and a further step is enabled, since it is stuck anyway. If the
+//     eviction is rejected by a PodDisruptionBudget, the ladder stops with errEvictionDeferred.
+//  2. a forced deletion with zero grace period, if ForceDelete is enabled
+//  3. removal of metadata.finalizers, if RemoveFinalizers is enabled
+//
+// In server side dry-run mode, every enabled step is sent with DryRun=All without waiting for the pod to be deleted.
+// The further steps wait for limiter like the first one, which is waited by the caller, a nil limiter means no
+// throttling. The last step which is sent and the grace period of the last deletion step are returned.
+func deletePod(ctx context.Context, clientSet kubernetes.Interface, limiter flowcontrol.RateLimiter, pod v1.Pod,
+	opts *options.KubePodTerminatorOptions, logger *zap.Logger) (deletion, error) {
+	var dryRun []string
+	if opts.DryRun == options.DryRunServer {
+		dryRun = []string{metav1.DryRunAll}
+	}
+
+	var d deletion
+	escalationWait := time.Duration(opts.EscalationWaitSeconds) * time.Second
+	if pod.DeletionTimestamp == nil || !(opts.ForceDelete || opts.RemoveFinalizers) {
+		d.gracePeriodSeconds = opts.GracePeriodSeconds
+		step, err := deleteGracefully(ctx, clientSet, pod, opts, dryRun, logger)
+		d.step = step
+		if err != nil {
+			return d, ignoreNotFound(err)
+		}
+
+		gracePeriod := time.Duration(opts.GracePeriodSeconds) * time.Second
+		if dryRun == nil && waitForPodDeletion(ctx, clientSet, pod, gracePeriod+escalationWait) {
+			return d, nil
+		}
+	} else {
+		logger.Info("pod is already being deleted, skipping the normal deletion", zap.String("step", stepDelete))
+	}
+
+	if opts.ForceDelete {
+		if err := waitForLimiter(ctx, limiter); err != nil {
+			return d, err
+		}
+
+		d = deletion{step: stepForceDelete, gracePeriodSeconds: 0}
+		logger.Info("pod still exists, deleting it forcefully", zap.String("step", stepForceDelete))
+		if err := clientSet.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name,
+			metav1.DeleteOptions{GracePeriodSeconds: &d.gracePeriodSeconds, DryRun: dryRun}); err != nil {
+			return d, ignoreNotFound(err)
+		}
+
+		if dryRun == nil && waitForPodDeletion(ctx, clientSet, pod, escalationWait) {
+			return d, nil
+		}
+	}
+
+	if opts.RemoveFinalizers {
+		if err := waitForLimiter(ctx, limiter); err != nil {
+			return d, err
+		}
+
+		d.step = stepRemoveFinalizers
+		logger.Info("pod still exists, removing its finalizers", zap.String("step", stepRemoveFinalizers),
+			zap.Strings("finalizers", pod.Finalizers))
+		if _, err := clientSet.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType,
+			[]byte(removeFinalizersPatch), metav1.PatchOptions{DryRun: dryRun}); err != nil {
+			return d, ignoreNotFound(err)
+		}
+
+		if dryRun == nil && waitForPodDeletion(ctx, clientSet, pod, escalationWait) {
+			return d, nil
+		}
+	}
+
+	if dryRun != nil {
+		return d, nil
+	}
+
+	return d, errPodStillExists
+}
+
+// deletion is the outcome of the escalation ladder of a pod
+type deletion struct {
+	// step is the last step of the escalation ladder which is sent
+	step string
+	// gracePeriodSeconds is the grace period of the last deletion step which is sent
+	gracePeriodSeconds int64
+}
+
+// deleteGracefully is the first step of the escalation ladder. Live pods are evicted through the Eviction API if
+// UseEviction is enabled, so that PodDisruptionBudgets are respected. Other pods are deleted with the grace period.
+// The step which is sent is returned.
+func deleteGracefully(ctx context.Context, clientSet kubernetes.Interface, pod v1.Pod,
+	opts *options.KubePodTerminatorOptions, dryRun []string, logger *zap.Logger) (string, error) {
+	deleteOptions := &metav1.DeleteOptions{GracePeriodSeconds: &opts.GracePeriodSeconds, DryRun: dryRun}
+	if !opts.UseEviction || !isLivePod(pod) {
+		logger.Info("deleting pod", zap.String("step", stepDelete), zap.Int64("gracePeriodSeconds", opts.GracePeriodSeconds))
+		return stepDelete, clientSet.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, *deleteOptions)
+	}
+
+	logger.Info("evicting pod", zap.String("step", stepEvict), zap.Int64("gracePeriodSeconds", opts.GracePeriodSeconds))
+	err := clientSet.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: deleteOptions,
+	})
+	if apierrors.IsTooManyRequests(err) {
+		return stepEvict, fmt.Errorf("%w: %s", errEvictionDeferred, err.Error())
+	}
+
+	return stepEvict, err
+}
+
+// waitForLimiter blocks until limiter permits the next request, a nil limiter permits it immediately
+func waitForLimiter(ctx context.Context, limiter flowcontrol.RateLimiter) error {
+	if limiter == nil {
+		return nil
+	}
+
+	return limiter.Wait(ctx)
+}
+
+// isLivePod returns true if the pod is not being deleted and not completed yet, so it may still hold endpoints
+func isLivePod(pod v1.Pod) bool {
+	return pod.DeletionTimestamp == nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed
+}
+
+// waitForPodDeletion polls the pod until it is deleted or the timeout expires, returns true if the pod is deleted.
+// A pod with the same name but a different UID, e.g. recreated by a StatefulSet, is treated as deleted.
+func waitForPodDeletion(ctx context.Context, clientSet kubernetes.Interface, pod v1.Pod, timeout time.Duration) bool {
+	err := wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := clientSet.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		if err != nil {
+			return false, nil
+		}
+
+		return current.UID != pod.UID, nil
+	})
+
+	return err == nil
+}
+
+// getErrorReason returns the short reason of a deletion error which is used as a metric label
+func getErrorReason(err error) string {
+	switch {
+	case errors.Is(err, errEvictionDeferred):
+		return "EvictionDeferred"
+	case errors.Is(err, errPodStillExists):
+		return "PodStillExists"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	}
+
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+
+	return "Unknown"
+}
+
+// ignoreNotFound returns nil if the error is a NotFound error, since the pod is deleted in that case
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
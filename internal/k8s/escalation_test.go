@@ -0,0 +1,194 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// getStuckFakeAPI returns a FakeAPI which ignores the deletion requests of pods, like a kubelet which is unreachable.
+// Pods are only deleted when their finalizers are removed.
+func getStuckFakeAPI() *FakeAPI {
+	api := getFakeAPI()
+	client := api.ClientSet.(*fake.Clientset)
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		return true, nil, client.Tracker().Delete(patchAction.GetResource(), patchAction.GetNamespace(),
+			patchAction.GetName())
+	})
+
+	return api
+}
+
+func countActions(api *FakeAPI, verb string) int {
+	var count int
+	for _, action := range api.ClientSet.(*fake.Clientset).Actions() {
+		if action.GetVerb() == verb && action.GetResource().Resource == "pods" {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestDeletePodEscalation(t *testing.T) {
+	cases := []struct {
+		caseName                      string
+		deletionTimestamp             *metav1.Time
+		forceDelete, removeFinalizers bool
+		dryRun                        string
+		expectedDeletes               int
+		expectedPatches               int
+		expectedStep                  string
+		shouldFail                    bool
+	}{
+		{"case1", nil, false, false, options.DryRunNone, 1, 0, stepDelete, true},
+		{"case2", nil, true, false, options.DryRunNone, 2, 0, stepForceDelete, true},
+		{"case3", nil, true, true, options.DryRunNone, 2, 1, stepRemoveFinalizers, false},
+		{"case4", &metav1.Time{Time: time.Now().Add(-time.Hour)}, true, true, options.DryRunNone, 1, 1,
+			stepRemoveFinalizers, false},
+		{"case5", &metav1.Time{Time: time.Now().Add(-time.Hour)}, false, false, options.DryRunNone, 1, 0, stepDelete,
+			true},
+		{"case6", nil, true, true, options.DryRunServer, 2, 1, stepRemoveFinalizers, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getStuckFakeAPI()
+			pod, err := api.createTerminatingPod("varnish-pod-1", "default", tc.deletionTimestamp)
+			assert.Nil(t, err)
+
+			testOpts := getDefaultOpts()
+			testOpts.GracePeriodSeconds = 0
+			testOpts.EscalationWaitSeconds = 0
+			testOpts.ForceDelete = tc.forceDelete
+			testOpts.RemoveFinalizers = tc.removeFinalizers
+			testOpts.DryRun = tc.dryRun
+
+			d, err := deletePod(context.Background(), api.ClientSet, nil, *pod, testOpts, logging.GetLogger())
+			assert.Equal(t, tc.shouldFail, err != nil)
+			assert.Equal(t, tc.expectedStep, d.step)
+			assert.Equal(t, tc.expectedDeletes, countActions(api, "delete"))
+			assert.Equal(t, tc.expectedPatches, countActions(api, "patch"))
+		})
+	}
+}
+
+func TestDeletePodNotFound(t *testing.T) {
+	api := getFakeAPI()
+	pod, err := api.createTerminatingPod("varnish-pod-1", "default", nil)
+	assert.Nil(t, err)
+
+	err = api.ClientSet.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	assert.Nil(t, err)
+
+	_, err = deletePod(context.Background(), api.ClientSet, nil, *pod, getDefaultOpts(), logging.GetLogger())
+	assert.Nil(t, err)
+}
+
+func TestDeletePodRateLimited(t *testing.T) {
+	api := getStuckFakeAPI()
+	pod, err := api.createTerminatingPod("varnish-pod-1", "default", nil)
+	assert.Nil(t, err)
+
+	testOpts := getDefaultOpts()
+	testOpts.GracePeriodSeconds = 0
+	testOpts.EscalationWaitSeconds = 0
+	testOpts.ForceDelete = true
+	testOpts.RemoveFinalizers = true
+
+	// the first step is waited by the caller, the token of the burst is taken by the forced deletion and the
+	// finalizer removal is throttled until the context is done
+	testOpts.DeleteQPS = 0.001
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	d, err := deletePod(ctx, api.ClientSet, NewDeleteRateLimiter(testOpts), *pod, testOpts, logging.GetLogger())
+	assert.NotNil(t, err)
+	assert.Equal(t, stepForceDelete, d.step)
+	assert.Equal(t, 2, countActions(api, "delete"))
+	assert.Equal(t, 0, countActions(api, "patch"))
+}
+
+func TestDeletePodEviction(t *testing.T) {
+	cases := []struct {
+		caseName          string
+		deletionTimestamp *metav1.Time
+		blockedByPDB      bool
+		expectedEvictions int
+		expectedDeletes   int
+		expectedStep      string
+		shouldDefer       bool
+	}{
+		{"case1", nil, false, 1, 0, stepEvict, false},
+		{"case2", nil, true, 1, 0, stepEvict, true},
+		{"case3", &metav1.Time{Time: time.Now().Add(-time.Hour)}, true, 0, 1, stepDelete, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			client := api.ClientSet.(*fake.Clientset)
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+
+				if tc.blockedByPDB {
+					return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+				}
+
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				return true, nil, client.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+			})
+
+			pod, err := api.createTerminatingPod("varnish-pod-1", "default", tc.deletionTimestamp)
+			assert.Nil(t, err)
+
+			testOpts := getDefaultOpts()
+			testOpts.GracePeriodSeconds = 0
+			testOpts.EscalationWaitSeconds = 0
+			testOpts.UseEviction = true
+
+			d, err := deletePod(context.Background(), api.ClientSet, nil, *pod, testOpts, logging.GetLogger())
+			assert.Equal(t, tc.shouldDefer, errors.Is(err, errEvictionDeferred))
+			assert.Equal(t, tc.expectedStep, d.step)
+			assert.Equal(t, tc.expectedEvictions, countActions(api, "create")-1)
+			assert.Equal(t, tc.expectedDeletes, countActions(api, "delete"))
+		})
+	}
+}
+
+func TestGetErrorReason(t *testing.T) {
+	cases := []struct {
+		caseName, expected string
+		err                error
+	}{
+		{"case1", "EvictionDeferred", fmt.Errorf("%w: blocked", errEvictionDeferred)},
+		{"case2", "PodStillExists", errPodStillExists},
+		{"case3", "Timeout", context.DeadlineExceeded},
+		{"case4", "Forbidden", apierrors.NewForbidden(v1.Resource("pods"), "varnish-pod-1", errors.New("denied"))},
+		{"case5", "Unknown", errors.New("connection refused")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, getErrorReason(tc.err))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// eventComponent is the source component of the events which are recorded by kube-pod-terminator
+	eventComponent = "kube-pod-terminator"
+	// eventReasonPodTerminated is the reason of the events which are recorded when a pod is terminated
+	eventReasonPodTerminated = "PodTerminated"
+	// eventFlushTimeout is the maximum duration which is waited for the pending events to be written on shutdown
+	eventFlushTimeout = 10 * time.Second
+)
+
+// eventRecorder counts the events which are not written to the cluster yet, so that they can be flushed on shutdown
+type eventRecorder struct {
+	record.EventRecorder
+	pending sync.WaitGroup
+}
+
+func (r *eventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.pending.Add(1)
+	r.EventRecorder.Event(object, eventtype, reason, message)
+}
+
+func (r *eventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.pending.Add(1)
+	r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+}
+
+func (r *eventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason,
+	messageFmt string, args ...interface{}) {
+	r.pending.Add(1)
+	r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+}
+
+// flush waits for the pending events to be written until the timeout, events which are dropped by an overloaded
+// broadcaster are never written, so the wait is bounded
+func (r *eventRecorder) flush(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// NewEventRecorder returns an EventRecorder which records the events to the cluster of the clientSet, and a function
+// which flushes the pending events and shuts the recorder down. The function must be called once the recorder is not
+// used anymore, otherwise the events which are still queued are lost and the broadcaster is leaked.
+func NewEventRecorder(clientSet kubernetes.Interface) (record.EventRecorder, func()) {
+	broadcaster := record.NewBroadcaster()
+	recorder := &eventRecorder{
+		EventRecorder: broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent}),
+	}
+
+	sink := &typedcorev1.EventSinkImpl{Interface: clientSet.CoreV1().Events("")}
+	broadcaster.StartEventWatcher(func(event *v1.Event) {
+		defer recorder.pending.Done()
+		if _, err := sink.Create(event); err != nil {
+			logging.GetLogger().Warn("an error occurred while recording event", zap.String("reason", event.Reason),
+				zap.String("kind", event.InvolvedObject.Kind), zap.String("name", event.InvolvedObject.Name),
+				zap.Error(err))
+		}
+	})
+
+	return recorder, func() {
+		recorder.flush(eventFlushTimeout)
+		broadcaster.Shutdown()
+	}
+}
+
+// recordTerminationEvents records an event on the controller of the terminated pod, if it has one, and on its
+// namespace, if it is found, so that kubectl describe explains why the pod is gone
+func recordTerminationEvents(recorder record.EventRecorder, c candidate, gracePeriodSeconds int64) {
+	message := fmt.Sprintf("pod %s is terminated by %s since %s, age %s, grace period %ds", c.pod.Name,
+		eventComponent, c.detector.Reason(), getPodAge(c.pod).Round(time.Second), gracePeriodSeconds)
+
+	if owner := metav1.GetControllerOf(&c.pod); owner != nil {
+		recorder.Event(&v1.ObjectReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+			Namespace:  c.pod.Namespace,
+			UID:        owner.UID,
+		}, v1.EventTypeNormal, eventReasonPodTerminated, message)
+	}
+
+	if c.namespace != nil {
+		recorder.Event(&v1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+			Name:       c.namespace.Name,
+			UID:        c.namespace.UID,
+		}, v1.EventTypeNormal, eventReasonPodTerminated, message)
+	}
+}
+
+// getPodAge returns the duration since the creation of the pod
+func getPodAge(pod v1.Pod) time.Duration {
+	return time.Since(pod.CreationTimestamp.Time)
+}
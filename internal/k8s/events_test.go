@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordTerminationEvents(t *testing.T) {
+	isController := true
+	cases := []struct {
+		caseName       string
+		ownerReference []metav1.OwnerReference
+		namespace      *v1.Namespace
+		expectedEvents int
+	}{
+		{"case1", nil, getNamespace(), 1},
+		{"case2", []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "varnish-7d4b9c",
+			UID: types.UID("1234"), Controller: &isController}}, getNamespace(), 2},
+		{"case3", nil, nil, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:              "varnish-pod-1",
+				Namespace:         "default",
+				OwnerReferences:   tc.ownerReference,
+				CreationTimestamp: metav1.Time{Time: time.Now().Add(-time.Hour)},
+			}}
+
+			recordTerminationEvents(recorder, candidate{pod: pod, detector: newEvictedDetector(getDefaultOpts()),
+				namespace: tc.namespace}, 30)
+			close(recorder.Events)
+
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+
+			assert.Len(t, events, tc.expectedEvents)
+			for _, event := range events {
+				assert.True(t, strings.HasPrefix(event, "Normal PodTerminated pod varnish-pod-1 is terminated"))
+				assert.Contains(t, event, "pod is evicted, age 1h0m0s, grace period 30s")
+			}
+		})
+	}
+}
+
+func TestNewEventRecorder(t *testing.T) {
+	api := getFakeAPI()
+	recorder, shutdown := NewEventRecorder(api.ClientSet)
+
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "varnish-pod-1", Namespace: "default"}}
+	recordTerminationEvents(recorder, candidate{pod: pod, detector: newEvictedDetector(getDefaultOpts()),
+		namespace: getNamespace()}, 30)
+
+	// pending events are written before shutdown returns
+	shutdown()
+
+	// events of the cluster scoped objects are recorded in the default namespace
+	events, err := api.ClientSet.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, events.Items, 1)
+	assert.Equal(t, eventReasonPodTerminated, events.Items[0].Reason)
+	assert.Equal(t, v1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "default",
+		UID: types.UID("5678")}, events.Items[0].InvolvedObject)
+}
+
+func getNamespace() *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: types.UID("5678")}}
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// getLoadingRules returns the loading rules of the kubeconfig path. A path which contains multiple paths separated
+// with the path list separator, like the KUBECONFIG environment variable, is merged with the same rules as kubectl,
+// otherwise the path must exist.
+func getLoadingRules(kubeConfigPath string) *clientcmd.ClientConfigLoadingRules {
+	if strings.ContainsRune(kubeConfigPath, filepath.ListSeparator) {
+		return &clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(kubeConfigPath)}
+	}
+
+	return &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfigPath}
+}
+
+// ListKubeConfigDir returns the paths of the kubeconfig files in the directory in order. Hidden files are skipped,
+// so that the ..data symlinks of ConfigMap and Secret volumes are not treated as clusters.
+func ListKubeConfigDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		// symlinks are followed, since the files of ConfigMap and Secret volumes are symlinks
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// WatchKubeConfigDir calls onChange when a file is created, removed or changed in the directory until stopCh is
+// closed. Errors of the watcher are passed to onChange.
+func WatchKubeConfigDir(stopCh <-chan struct{}, dir string, onChange func(err error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(dir); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer func() {
+			_ = watcher.Close()
+		}()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op != fsnotify.Chmod {
+					onChange(nil)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				onChange(err)
+			}
+		}
+	}()
+
+	return nil
+}
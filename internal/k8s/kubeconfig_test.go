@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetConfigMerged(t *testing.T) {
+	// the first file wins for the current-context, like the KUBECONFIG environment variable
+	kubeConfigPath := "../../test/kubeconfig" + string(filepath.ListSeparator) + "../../test/multi_context_kubeconfig"
+	restConfig, err := GetConfig(kubeConfigPath, "", false, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://192.168.49.2:8443", restConfig.Host)
+
+	restConfig, err = GetConfig(kubeConfigPath, "staging", false, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://192.168.49.3:8443", restConfig.Host)
+
+	contexts, err := GetContexts(kubeConfigPath, "all")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"minikube", "production", "staging"}, contexts)
+
+	// missing files are skipped while merging, but an explicit path must exist
+	contexts, err = GetContexts("../../test/nonexistent_kubeconfig"+string(filepath.ListSeparator)+
+		"../../test/kubeconfig", "all")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"minikube"}, contexts)
+}
+
+func TestListKubeConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"staging", "production", ".hidden"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte("kind: Config"), 0600))
+	}
+
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "..data"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "..data", "development"), []byte("kind: Config"), 0600))
+	assert.Nil(t, os.Symlink(filepath.Join("..data", "development"), filepath.Join(dir, "development")))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+
+	paths, err := ListKubeConfigDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "development"), filepath.Join(dir, "production"),
+		filepath.Join(dir, "staging")}, paths)
+
+	_, err = ListKubeConfigDir(filepath.Join(dir, "nonexistent"))
+	assert.NotNil(t, err)
+}
+
+func TestWatchKubeConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	changes := make(chan error, 10)
+	assert.Nil(t, WatchKubeConfigDir(stopCh, dir, func(err error) {
+		changes <- err
+	}))
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "staging"), []byte("kind: Config"), 0600))
+	select {
+	case err := <-changes:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change for the new kubeconfig")
+	}
+
+	assert.NotNil(t, WatchKubeConfigDir(stopCh, filepath.Join(dir, "nonexistent"), func(err error) {}))
+}
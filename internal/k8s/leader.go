@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElection is the Lease based leader election of a cluster, so that only one of the replicas terminates the
+// pods of that cluster while the others stand by
+type LeaderElection struct {
+	elector     *leaderelection.LeaderElector
+	leading     atomic.Bool
+	retryPeriod time.Duration
+}
+
+// NewLeaderElection returns a LeaderElection which competes for the Lease with the identity. An empty identity is
+// replaced with the hostname and a random suffix.
+func NewLeaderElection(clientSet kubernetes.Interface, opts *options.KubePodTerminatorOptions, identity string,
+	logger *zap.Logger) (*LeaderElection, error) {
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+
+		identity = fmt.Sprintf("%s_%s", hostname, uuid.NewUUID())
+	}
+
+	logger = logger.With(zap.String("lease", opts.LeaderElectionNamespace+"/"+opts.LeaderElectionLeaseName),
+		zap.String("identity", identity))
+	le := &LeaderElection{retryPeriod: time.Duration(opts.LeaderElectionRetryPeriodSeconds) * time.Second}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      opts.LeaderElectionLeaseName,
+				Namespace: opts.LeaderElectionNamespace,
+			},
+			Client:     clientSet.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   time.Duration(opts.LeaderElectionLeaseDurationSeconds) * time.Second,
+		RenewDeadline:   time.Duration(opts.LeaderElectionRenewDeadlineSeconds) * time.Second,
+		RetryPeriod:     le.retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            opts.LeaderElectionLeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				le.leading.Store(true)
+				logger.Info("started leading, pods will be terminated by this replica")
+				<-ctx.Done()
+				le.leading.Store(false)
+			},
+			OnStoppedLeading: func() {
+				le.leading.Store(false)
+				logger.Info("stopped leading")
+			},
+			OnNewLeader: func(leader string) {
+				logger.Info("new leader is elected", zap.String("leader", leader))
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	le.elector = elector
+
+	return le, nil
+}
+
+// Run competes for the Lease until ctx is done, it competes again after losing the leadership
+func (le *LeaderElection) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, le.elector.Run, le.retryPeriod)
+}
+
+// IsLeader returns true if this replica is holding the Lease
+func (le *LeaderElection) IsLeader() bool {
+	return le.leading.Load()
+}
@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getLeaderElectionOpts() *options.KubePodTerminatorOptions {
+	testOpts := getDefaultOpts()
+	testOpts.LeaderElect = true
+	testOpts.LeaderElectionLeaseName = "kube-pod-terminator"
+	testOpts.LeaderElectionNamespace = "default"
+	testOpts.LeaderElectionLeaseDurationSeconds = 3
+	testOpts.LeaderElectionRenewDeadlineSeconds = 2
+	testOpts.LeaderElectionRetryPeriodSeconds = 1
+
+	return testOpts
+}
+
+func TestLeaderElection(t *testing.T) {
+	api := getFakeAPI()
+	testOpts := getLeaderElectionOpts()
+
+	first, err := NewLeaderElection(api.ClientSet, testOpts, "replica-1", logging.GetLogger())
+	assert.Nil(t, err)
+	second, err := NewLeaderElection(api.ClientSet, testOpts, "replica-2", logging.GetLogger())
+	assert.Nil(t, err)
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	defer firstCancel()
+	go first.Run(firstCtx)
+	assert.Eventually(t, first.IsLeader, 5*time.Second, 100*time.Millisecond)
+
+	secondCtx, secondCancel := context.WithCancel(context.Background())
+	defer secondCancel()
+	go second.Run(secondCtx)
+	assert.Never(t, second.IsLeader, 2*time.Second, 100*time.Millisecond)
+
+	lease, err := api.ClientSet.CoordinationV1().Leases("default").Get(context.Background(), "kube-pod-terminator",
+		metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "replica-1", *lease.Spec.HolderIdentity)
+
+	// the first replica releases the Lease on shutdown, so the second one takes over
+	firstCancel()
+	assert.Eventually(t, second.IsLeader, 10*time.Second, 100*time.Millisecond)
+	assert.False(t, first.IsLeader())
+}
+
+func TestNewLeaderElectionInvalidDurations(t *testing.T) {
+	testOpts := getLeaderElectionOpts()
+	testOpts.LeaderElectionRenewDeadlineSeconds = 5
+
+	_, err := NewLeaderElection(getFakeAPI().ClientSet, testOpts, "", logging.GetLogger())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,249 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// defaultInformerSyncTimeout bounds the initial sync of the informer cache if the list timeout is disabled, so that an
+// unreachable cluster or an expired credential does not block the worker forever
+const defaultInformerSyncTimeout = 5 * time.Minute
+
+// PodLister lists the pods which are evaluated by the detectors
+type PodLister interface {
+	// ListPods returns the pods in the given namespace which are selected by listOptions, metav1.NamespaceAll means
+	// all namespaces
+	ListPods(ctx context.Context, namespace string, listOptions metav1.ListOptions) ([]v1.Pod, error)
+}
+
+// apiPodLister lists the pods directly from the kube-apiserver on each call
+type apiPodLister struct {
+	clientSet kubernetes.Interface
+}
+
+// NewAPIPodLister returns a PodLister which lists the pods directly from the kube-apiserver, it is suitable for
+// one-shot runs where keeping a local cache is not worth it
+func NewAPIPodLister(clientSet kubernetes.Interface) PodLister {
+	return &apiPodLister{clientSet: clientSet}
+}
+
+func (l *apiPodLister) ListPods(ctx context.Context, namespace string, listOptions metav1.ListOptions) ([]v1.Pod, error) {
+	pods, err := l.clientSet.CoreV1().Pods(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// InformerPodLister serves the pods from the local cache of the shared pod informers, so that the kube-apiserver
+// is not listed on each run. It also signals on Updates when a pod in the cache starts to be matched by a detector.
+type InformerPodLister struct {
+	// listers are keyed by the namespace which their informers watch, metav1.NamespaceAll means all namespaces
+	listers  map[string]corev1listers.PodLister
+	resolver atomic.Pointer[detectorResolver]
+	updates  chan struct{}
+	// eventMu serializes the events of the informers, since the resolver caches the detectors of the namespaces
+	eventMu sync.Mutex
+}
+
+// NewInformerPodLister starts a shared pod informer for each of the list namespaces of the options which watches the
+// target pods, and blocks until their caches are synced. The sync times out after the list timeout of the options, in
+// which case the informers are stopped and an error is returned. The informers resync the cache with the
+// TickerIntervalMinutes of the options and stop when ctx is done.
+func NewInformerPodLister(ctx context.Context, clientSet kubernetes.Interface,
+	opts *options.KubePodTerminatorOptions) (*InformerPodLister, error) {
+	l := &InformerPodLister{
+		listers: make(map[string]corev1listers.PodLister),
+		updates: make(chan struct{}, 1),
+	}
+	l.SetOptions(opts)
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			l.onEvent(nil, obj)
+		},
+		UpdateFunc: l.onEvent,
+	}
+
+	var factories []informers.SharedInformerFactory
+	for _, namespace := range getListNamespaces(opts) {
+		factory := newPodInformerFactory(clientSet, opts, namespace)
+		podInformer := factory.Core().V1().Pods()
+		if _, err := podInformer.Informer().AddEventHandler(handler); err != nil {
+			return nil, err
+		}
+
+		l.listers[namespace] = podInformer.Lister()
+		factories = append(factories, factory)
+	}
+
+	stopCh := make(chan struct{})
+	stopOnDone := context.AfterFunc(ctx, func() {
+		close(stopCh)
+	})
+
+	syncCtx, cancel := context.WithTimeout(ctx, getInformerSyncTimeout(opts))
+	defer cancel()
+
+	for _, factory := range factories {
+		factory.Start(stopCh)
+	}
+
+	for _, factory := range factories {
+		for _, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+			if synced {
+				continue
+			}
+
+			// the informers of a failed worker must not keep retrying in the background after it is restarted
+			if stopOnDone() {
+				close(stopCh)
+			}
+
+			for _, f := range factories {
+				f.Shutdown()
+			}
+
+			return nil, errors.New("timed out waiting for the pod informer cache to be synced")
+		}
+	}
+
+	return l, nil
+}
+
+// newPodInformerFactory returns a shared informer factory which watches the target pods of the options in the
+// namespace, metav1.NamespaceAll means all namespaces
+func newPodInformerFactory(clientSet kubernetes.Interface, opts *options.KubePodTerminatorOptions,
+	namespace string) informers.SharedInformerFactory {
+	resync := time.Duration(opts.TickerIntervalMinutes) * time.Minute
+	return informers.NewSharedInformerFactoryWithOptions(clientSet, resync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = opts.PodSelector
+			listOptions.FieldSelector = opts.PodFieldSelector
+		}))
+}
+
+// getInformerSyncTimeout returns the timeout of the initial sync of the informer cache, which is the list timeout
+// unless it is disabled
+func getInformerSyncTimeout(opts *options.KubePodTerminatorOptions) time.Duration {
+	if opts.ListTimeoutSeconds <= 0 {
+		return defaultInformerSyncTimeout
+	}
+
+	return time.Duration(opts.ListTimeoutSeconds) * time.Second
+}
+
+// ListPods applies the selectors of listOptions on the local cache of the informer which watches the namespace, the
+// same way the kube-apiserver would
+func (l *InformerPodLister) ListPods(_ context.Context, namespace string, listOptions metav1.ListOptions) ([]v1.Pod, error) {
+	labelSelector, err := labels.Parse(listOptions.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldSelector, err := fields.ParseSelector(listOptions.FieldSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := l.listers[namespace]
+	if !ok {
+		lister, ok = l.listers[metav1.NamespaceAll]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("pods in namespace %q are not watched by the pod informers", namespace)
+	}
+
+	cachedPods, err := lister.Pods(namespace).List(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make([]v1.Pod, 0, len(cachedPods))
+	for _, pod := range cachedPods {
+		if fieldSelector.Matches(getPodFields(pod)) {
+			pods = append(pods, *pod)
+		}
+	}
+
+	return pods, nil
+}
+
+// SetOptions replaces the options which the detectors are built with while signaling on Updates, e.g. after the
+// config file is reloaded. The namespaces and the resync period of the informers are not changed.
+func (l *InformerPodLister) SetOptions(opts *options.KubePodTerminatorOptions) {
+	l.resolver.Store(newDetectorResolver(opts))
+}
+
+// Updates returns a channel which receives a signal when a pod in the cache starts to be matched by any detector,
+// either because it is added or because it is updated. Signals are coalesced while nobody is receiving.
+func (l *InformerPodLister) Updates() <-chan struct{} {
+	return l.updates
+}
+
+// onEvent signals on updates channel if the new pod is matched by a detector which did not match the old one,
+// so that the pods which are still stuck after a run do not trigger the next run over and over
+func (l *InformerPodLister) onEvent(oldObj, newObj interface{}) {
+	newPod, ok := newObj.(*v1.Pod)
+	if !ok {
+		return
+	}
+
+	l.eventMu.Lock()
+	defer l.eventMu.Unlock()
+
+	oldPod, _ := oldObj.(*v1.Pod)
+	resolver := l.resolver.Load()
+	if !resolver.selects(*newPod) {
+		return
+	}
+
+	for _, d := range getRegisteredDetectors() {
+		detector := resolver.detectorFor(d, newPod.Namespace)
+		if detector != nil && detector.Match(*newPod) && (oldPod == nil || !detector.Match(*oldPod)) {
+			select {
+			case l.updates <- struct{}{}:
+			default:
+			}
+
+			return
+		}
+	}
+}
+
+// getListOptions returns the list options which select the target pods of the options
+func getListOptions(opts *options.KubePodTerminatorOptions) metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: opts.PodSelector, FieldSelector: opts.PodFieldSelector}
+}
+
+// getPodFields returns the fields of the pod which are supported by the field selectors of the kube-apiserver
+func getPodFields(pod *v1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":            pod.Name,
+		"metadata.namespace":       pod.Namespace,
+		"spec.nodeName":            pod.Spec.NodeName,
+		"spec.restartPolicy":       string(pod.Spec.RestartPolicy),
+		"spec.schedulerName":       pod.Spec.SchedulerName,
+		"spec.serviceAccountName":  pod.Spec.ServiceAccountName,
+		"status.phase":             string(pod.Status.Phase),
+		"status.podIP":             pod.Status.PodIP,
+		"status.nominatedNodeName": pod.Status.NominatedNodeName,
+	}
+}
@@ -0,0 +1,169 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestAPIPodLister(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	_, err := api.createEvictedPod("varnish-pod-1", "default")
+	assert.Nil(t, err)
+	_, err = api.createEvictedPod("varnish-pod-2", "kube-system")
+	assert.Nil(t, err)
+
+	lister := NewAPIPodLister(api.ClientSet)
+	pods, err := lister.ListPods(context.Background(), metav1.NamespaceAll, metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pods, 2)
+
+	pods, err = lister.ListPods(context.Background(), "default", metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pods, 1)
+}
+
+func TestInformerPodLister(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default"
+
+	_, err := api.createEvictedPod("varnish-pod-1", "default")
+	assert.Nil(t, err)
+	_, err = api.createEvictedPod("varnish-pod-2", "kube-system")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lister, err := NewInformerPodLister(ctx, api.ClientSet, testOpts)
+	assert.Nil(t, err)
+	assert.NotNil(t, lister)
+
+	pods, err := lister.ListPods(context.Background(), "default", metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pods, 1)
+
+	pods, err = lister.ListPods(context.Background(), "default",
+		metav1.ListOptions{FieldSelector: "status.phase=Succeeded"})
+	assert.Nil(t, err)
+	assert.Len(t, pods, 0)
+
+	pods, err = lister.ListPods(context.Background(), "default",
+		metav1.ListOptions{LabelSelector: "team=batch"})
+	assert.Nil(t, err)
+	assert.Len(t, pods, 0)
+
+	_, err = lister.ListPods(context.Background(), "default",
+		metav1.ListOptions{FieldSelector: "status.phase"})
+	assert.NotNil(t, err)
+
+	_, err = lister.ListPods(context.Background(), "default",
+		metav1.ListOptions{LabelSelector: "team in (batch"})
+	assert.NotNil(t, err)
+
+	// the evicted pod which is added to the cache on the initial list must be signaled
+	select {
+	case <-lister.Updates():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a signal for the evicted pod")
+	}
+
+	// a pod which is not matched by any detector must not be signaled
+	_, err = api.createTerminatingPod("varnish-pod-3", "default", nil)
+	assert.Nil(t, err)
+	select {
+	case <-lister.Updates():
+		t.Fatal("unexpected signal for the running pod")
+	case <-time.After(time.Second):
+	}
+
+	Run(context.Background(), testOpts, api.ClientSet, lister, record.NewFakeRecorder(100), nil, "")
+
+	_, err = api.ClientSet.CoreV1().Pods("default").Get(context.Background(), "varnish-pod-1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestInformerPodListerNamespaces(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default,prod"
+
+	for _, namespace := range []string{"default", "prod", "kube-system"} {
+		_, err := api.createEvictedPod("varnish-pod-1", namespace)
+		assert.Nil(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lister, err := NewInformerPodLister(ctx, api.ClientSet, testOpts)
+	assert.Nil(t, err)
+	assert.Len(t, lister.listers, 2)
+
+	for _, namespace := range []string{"default", "prod"} {
+		pods, err := lister.ListPods(context.Background(), namespace, metav1.ListOptions{})
+		assert.Nil(t, err)
+		assert.Len(t, pods, 1)
+	}
+
+	// the pods in the other namespaces are neither watched nor listed
+	_, err = lister.ListPods(context.Background(), "kube-system", metav1.ListOptions{})
+	assert.NotNil(t, err)
+	_, err = lister.ListPods(context.Background(), metav1.NamespaceAll, metav1.ListOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestInformerPodListerSyncTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	cases := []struct {
+		caseName string
+		reactor  k8stesting.ReactionFunc
+	}{
+		{"case1", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewUnauthorized("token has expired")
+		}},
+		{"case2", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			<-unblock
+			return true, &v1.PodList{}, nil
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			api.ClientSet.(*fake.Clientset).PrependReactor("list", "pods", tc.reactor)
+
+			testOpts := getDefaultOpts()
+			testOpts.ListTimeoutSeconds = 1
+
+			start := time.Now()
+			lister, err := NewInformerPodLister(context.Background(), api.ClientSet, testOpts)
+			assert.Nil(t, lister)
+			assert.Equal(t, errors.New("timed out waiting for the pod informer cache to be synced"), err)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+
+	testOpts := getDefaultOpts()
+	testOpts.ListTimeoutSeconds = 0
+	assert.Equal(t, defaultInformerSyncTimeout, getInformerSyncTimeout(testOpts))
+}
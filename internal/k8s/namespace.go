@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// getListNamespaces returns the namespaces which the pods are listed in. If all of the included namespaces are names
+// without a glob pattern, each of them is listed separately, so that the pods can be listed with the permissions in
+// those namespaces only. Otherwise the pods are listed in all namespaces and the target namespaces are selected
+// locally.
+func getListNamespaces(opts *options.KubePodTerminatorOptions) []string {
+	included := opts.IncludedNamespaces()
+	if included == nil {
+		return []string{metav1.NamespaceAll}
+	}
+
+	namespaces := make([]string, 0, len(included))
+	for _, namespace := range included {
+		if strings.ContainsAny(namespace, `*?[\`) {
+			return []string{metav1.NamespaceAll}
+		}
+
+		// excluded namespaces are never touched, so there is no need to list them
+		if opts.SelectsNamespace(namespace) && !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// getSelectedNamespaces returns the set of the namespaces which are selected by the NamespaceSelector of the
+// options, nil if there is no NamespaceSelector
+func getSelectedNamespaces(ctx context.Context, clientSet kubernetes.Interface,
+	opts *options.KubePodTerminatorOptions) (map[string]struct{}, error) {
+	if opts.NamespaceSelector == "" {
+		return nil, nil
+	}
+
+	namespaces, err := clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: opts.NamespaceSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]struct{}, len(namespaces.Items))
+	for _, namespace := range namespaces.Items {
+		selected[namespace.Name] = struct{}{}
+	}
+
+	return selected, nil
+}
+
+// filterPodsByNamespace returns the pods in the selected namespaces, all of the pods if selected is nil
+func filterPodsByNamespace(pods []v1.Pod, selected map[string]struct{}) []v1.Pod {
+	if selected == nil {
+		return pods
+	}
+
+	filtered := make([]v1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if _, ok := selected[pod.Namespace]; ok {
+			filtered = append(filtered, pod)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,99 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGetListNamespaces(t *testing.T) {
+	cases := []struct {
+		caseName, namespace, excludeNamespaces string
+		expected                               []string
+	}{
+		{"case1", "all", "", []string{metav1.NamespaceAll}},
+		{"case2", "default", "", []string{"default"}},
+		{"case3", "default,prod", "", []string{"default", "prod"}},
+		{"case4", "team-*", "", []string{metav1.NamespaceAll}},
+		{"case5", "default,team-*", "", []string{metav1.NamespaceAll}},
+		{"case6", "default,prod,default", "prod", []string{"default"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			testOpts := getDefaultOpts()
+			testOpts.Namespace = tc.namespace
+			testOpts.ExcludeNamespaces = tc.excludeNamespaces
+			assert.Equal(t, tc.expected, getListNamespaces(testOpts))
+		})
+	}
+}
+
+func TestRunNamespaceList(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default,prod"
+
+	for _, namespace := range []string{"default", "prod", "kube-system"} {
+		_, err := api.createEvictedPod("varnish-pod-1", namespace)
+		assert.Nil(t, err)
+	}
+
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
+
+	// each of the namespaces is listed separately instead of all namespaces
+	var namespaces []string
+	for _, action := range api.ClientSet.(*fake.Clientset).Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			namespaces = append(namespaces, action.GetNamespace())
+		}
+	}
+
+	assert.Equal(t, []string{"default", "prod"}, namespaces)
+
+	for namespace, expectedDeleted := range map[string]bool{"default": true, "prod": true, "kube-system": false} {
+		_, err := api.ClientSet.CoreV1().Pods(namespace).Get(context.Background(), "varnish-pod-1", metav1.GetOptions{})
+		assert.Equal(t, expectedDeleted, err != nil, namespace)
+	}
+}
+
+func TestRunNamespaceFilters(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "team-*,prod"
+	testOpts.ExcludeNamespaces = "team-b"
+	testOpts.NamespaceSelector = "terminator.io/enabled=true"
+
+	namespaces := map[string]string{"team-a": "true", "team-b": "true", "team-c": "false", "prod": "true",
+		"kube-system": "true"}
+	for name, enabled := range namespaces {
+		_, err := api.ClientSet.CoreV1().Namespaces().Create(context.Background(), &v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"terminator.io/enabled": enabled}},
+		}, metav1.CreateOptions{})
+		assert.Nil(t, err)
+
+		_, err = api.createEvictedPod("varnish-pod-1", name)
+		assert.Nil(t, err)
+	}
+
+	selected, err := getSelectedNamespaces(context.Background(), api.ClientSet, testOpts)
+	assert.Nil(t, err)
+	assert.Len(t, selected, 4)
+
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
+
+	for name, expectedDeleted := range map[string]bool{"team-a": true, "team-b": false, "team-c": false,
+		"prod": true, "kube-system": false} {
+		_, err := api.ClientSet.CoreV1().Pods(name).Get(context.Background(), "varnish-pod-1", metav1.GetOptions{})
+		assert.Equal(t, expectedDeleted, err != nil, name)
+	}
+}
@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// RunSuccess means all the candidates of the run are processed without any failure
+	RunSuccess = "success"
+	// RunPartialFailure means all the candidates of the run are processed, but some of them could not be terminated
+	RunPartialFailure = "partial-failure"
+	// RunFailure means the run is failed before processing the candidates or is interrupted by the shutdown
+	RunFailure = "failure"
+	// RunAborted means the run is aborted by the safety limits and no pod is terminated
+	RunAborted = "aborted"
+
+	// PodTerminated means the pod is terminated
+	PodTerminated = "terminated"
+	// PodFailed means the pod could not be terminated
+	PodFailed = "failed"
+	// PodDeferred means the eviction of the pod is rejected by a PodDisruptionBudget and left to the next runs
+	PodDeferred = "deferred"
+	// PodSkipped means the pod is not touched because of the safety limits or the shutdown
+	PodSkipped = "skipped"
+	// PodDryRun means the pod would be terminated if dry-run was disabled
+	PodDryRun = "dry-run"
+
+	// actionNone is the action of the pods which are not touched
+	actionNone = "none"
+)
+
+// Report is the result of a run on a cluster
+type Report struct {
+	Cluster string `json:"cluster"`
+	// Result is one of RunSuccess, RunPartialFailure, RunFailure or RunAborted
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// Pods are the candidates of the run in the order of their namespaces and names
+	Pods []PodReport `json:"pods"`
+}
+
+// PodReport is the outcome of a single candidate of a run
+type PodReport struct {
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	Detector          string `json:"detector"`
+	AgeInStateSeconds int64  `json:"ageInStateSeconds"`
+	// Action is the last step of the escalation ladder which is sent for the pod, none if it is not touched
+	Action string `json:"action"`
+	// Result is one of PodTerminated, PodFailed, PodDeferred, PodSkipped or PodDryRun
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newPodReport returns the report of the candidate with the action and the result, err is optional
+func newPodReport(c candidate, action, result string, err error) PodReport {
+	report := PodReport{
+		Namespace:         c.pod.Namespace,
+		Name:              c.pod.Name,
+		Detector:          c.detector.Name(),
+		AgeInStateSeconds: int64(getStateAge(c.detector, c.pod) / time.Second),
+		Action:            action,
+		Result:            result,
+	}
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	return report
+}
+
+// podReports collects the reports of the candidates which are terminated concurrently
+type podReports struct {
+	mu      sync.Mutex
+	reports []PodReport
+}
+
+func (r *podReports) add(report PodReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, report)
+}
+
+// getSkippedReports returns the reports of the candidates which are not in processed, with err as the reason
+func getSkippedReports(candidates, processed []candidate, err error) []PodReport {
+	processedPods := make(map[string]struct{}, len(processed))
+	for _, c := range processed {
+		processedPods[c.pod.Namespace+"/"+c.pod.Name] = struct{}{}
+	}
+
+	var reports []PodReport
+	for _, c := range candidates {
+		if _, ok := processedPods[c.pod.Namespace+"/"+c.pod.Name]; !ok {
+			reports = append(reports, newPodReport(c, actionNone, PodSkipped, err))
+		}
+	}
+
+	return reports
+}
+
+// finish sets the result of the run and sorts the pods
+func (r *Report) finish(result string, err error) *Report {
+	r.Result = result
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	sort.Slice(r.Pods, func(i, j int) bool {
+		if r.Pods[i].Namespace != r.Pods[j].Namespace {
+			return r.Pods[i].Namespace < r.Pods[j].Namespace
+		}
+
+		return r.Pods[i].Name < r.Pods[j].Name
+	})
+
+	return r
+}
+
+// getPartialFailure returns an error which wraps ErrPartialFailure if some of the pods are failed, nil otherwise
+func (r *Report) getPartialFailure() error {
+	var failed int
+	for _, pod := range r.Pods {
+		if pod.Result == PodFailed {
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d of %d pods are failed", ErrPartialFailure, failed, len(r.Pods))
+}
@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRunReport(t *testing.T) {
+	cases := []struct {
+		caseName           string
+		dryRun             string
+		maxDeletionsPerRun int32
+		expected           []PodReport
+	}{
+		{"case1", options.DryRunNone, 0, []PodReport{
+			{Namespace: "default", Name: "varnish-pod-1", Detector: "evicted", Action: stepDelete, Result: PodTerminated},
+			{Namespace: "default", Name: "varnish-pod-2", Detector: "evicted", Action: stepDelete, Result: PodTerminated},
+		}},
+		{"case2", options.DryRunNone, 1, []PodReport{
+			{Namespace: "default", Name: "varnish-pod-1", Detector: "evicted", Action: stepDelete, Result: PodTerminated},
+			{Namespace: "default", Name: "varnish-pod-2", Detector: "evicted", Action: actionNone, Result: PodSkipped,
+				Error: errLeftToNextRuns.Error()},
+		}},
+		{"case3", options.DryRunClient, 0, []PodReport{
+			{Namespace: "default", Name: "varnish-pod-1", Detector: "evicted", Action: actionNone, Result: PodDryRun},
+			{Namespace: "default", Name: "varnish-pod-2", Detector: "evicted", Action: actionNone, Result: PodDryRun},
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			testOpts := getDefaultOpts()
+			testOpts.Namespace = "default"
+			testOpts.GracePeriodSeconds = 0
+			testOpts.DryRun = tc.dryRun
+			testOpts.MaxDeletionsPerRun = tc.maxDeletionsPerRun
+
+			// pods are reported in order regardless of the order they are created and terminated
+			for _, name := range []string{"varnish-pod-2", "varnish-pod-1"} {
+				_, err := api.createEvictedPod(name, "default")
+				assert.Nil(t, err)
+			}
+
+			report, err := Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet),
+				record.NewFakeRecorder(100), nil, "https://report")
+			assert.Nil(t, err)
+			assert.Equal(t, "https://report", report.Cluster)
+			assert.Equal(t, RunSuccess, report.Result)
+			for i := range report.Pods {
+				report.Pods[i].AgeInStateSeconds = 0
+			}
+
+			assert.Equal(t, tc.expected, report.Pods)
+		})
+	}
+}
+
+func TestGetSkippedReports(t *testing.T) {
+	detector := newEvictedDetector(getDefaultOpts())
+	var candidates []candidate
+	for _, name := range []string{"varnish-pod-1", "varnish-pod-2", "varnish-pod-3"} {
+		candidates = append(candidates, candidate{pod: v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name,
+			Namespace: "default"}}, detector: detector})
+	}
+
+	err := errors.New("skipped")
+	reports := getSkippedReports(candidates, candidates[1:2], err)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "varnish-pod-1", reports[0].Name)
+	assert.Equal(t, "varnish-pod-3", reports[1].Name)
+	assert.Equal(t, PodSkipped, reports[0].Result)
+	assert.Equal(t, "skipped", reports[0].Error)
+
+	assert.Len(t, getSkippedReports(candidates, nil, err), 3)
+}
@@ -0,0 +1,128 @@
+package k8s
+
+import (
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// detectorResolver builds the registered detectors with the effective options of each namespace, which are the
+// global options overridden by the matching policy, and caches them
+type detectorResolver struct {
+	opts      *options.KubePodTerminatorOptions
+	nsOptions map[string]*options.KubePodTerminatorOptions
+	detectors map[string]Detector
+	selectors map[string]podSelector
+}
+
+// podSelector is the parsed label and field selectors of the effective options of a namespace
+type podSelector struct {
+	labels labels.Selector
+	fields fields.Selector
+}
+
+func newDetectorResolver(opts *options.KubePodTerminatorOptions) *detectorResolver {
+	return &detectorResolver{
+		opts:      opts,
+		nsOptions: make(map[string]*options.KubePodTerminatorOptions),
+		detectors: make(map[string]Detector),
+		selectors: make(map[string]podSelector),
+	}
+}
+
+// optionsFor returns the effective options of the namespace
+func (r *detectorResolver) optionsFor(namespace string) *options.KubePodTerminatorOptions {
+	nsOpts, ok := r.nsOptions[namespace]
+	if !ok {
+		nsOpts = r.opts.ForNamespace(namespace)
+		r.nsOptions[namespace] = nsOpts
+	}
+
+	return nsOpts
+}
+
+// selects returns true if the namespace of the pod is targeted and the pod is selected by the selectors of the
+// effective options of its namespace. Global selectors are already pushed into the list calls, this is where the
+// selectors of the policies take effect.
+func (r *detectorResolver) selects(pod v1.Pod) bool {
+	if !r.opts.SelectsNamespace(pod.Namespace) {
+		return false
+	}
+
+	selector, ok := r.selectors[pod.Namespace]
+	if !ok {
+		nsOpts := r.optionsFor(pod.Namespace)
+		labelSelector, err := labels.Parse(nsOpts.PodSelector)
+		if err != nil {
+			labelSelector = labels.Nothing()
+		}
+
+		fieldSelector, err := fields.ParseSelector(nsOpts.PodFieldSelector)
+		if err != nil {
+			fieldSelector = fields.Nothing()
+		}
+
+		selector = podSelector{labels: labelSelector, fields: fieldSelector}
+		r.selectors[pod.Namespace] = selector
+	}
+
+	return selector.labels.Matches(labels.Set(pod.Labels)) && selector.fields.Matches(getPodFields(&pod))
+}
+
+// detectorFor returns the detector which is built with the effective options of the namespace, nil if it is
+// disabled for the namespace
+func (r *detectorResolver) detectorFor(d registeredDetector, namespace string) Detector {
+	key := d.name + "/" + namespace
+	detector, ok := r.detectors[key]
+	if !ok {
+		detector = d.factory(r.optionsFor(namespace))
+		r.detectors[key] = detector
+	}
+
+	return detector
+}
+
+// enabled returns the detector which is built with the global options or with the first policy which enables it,
+// nil if the detector is disabled everywhere
+func (r *detectorResolver) enabled(d registeredDetector) Detector {
+	if detector := d.factory(r.opts); detector != nil {
+		return detector
+	}
+
+	for _, policy := range r.opts.Policies {
+		if detector := d.factory(policy.Apply(r.opts)); detector != nil {
+			return detector
+		}
+	}
+
+	return nil
+}
+
+// detectPods returns the candidates which are matched by the detector with the effective options of their
+// namespaces and not matched by any other detector before. If overrides is not nil, it is called with the effective
+// options of each pod before it is matched, and the pod is matched with the options which it returns.
+func (r *detectorResolver) detectPods(d registeredDetector, pods []v1.Pod, matched map[string]struct{},
+	overrides func(pod v1.Pod, opts *options.KubePodTerminatorOptions) *options.KubePodTerminatorOptions) []candidate {
+	var result []candidate
+	for _, pod := range pods {
+		key := pod.Namespace + "/" + pod.Name
+		if _, ok := matched[key]; ok || !r.selects(pod) {
+			continue
+		}
+
+		opts, detector := r.optionsFor(pod.Namespace), r.detectorFor(d, pod.Namespace)
+		if overrides != nil {
+			if overridden := overrides(pod, opts); overridden != opts {
+				opts, detector = overridden, d.factory(overridden)
+			}
+		}
+
+		if detector != nil && detector.Match(pod) {
+			matched[key] = struct{}{}
+			result = append(result, candidate{pod: pod, detector: detector, opts: opts})
+		}
+	}
+
+	return result
+}
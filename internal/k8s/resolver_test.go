@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDetectorResolver(t *testing.T) {
+	terminatingStateMinutes := int32(10)
+	terminateEvicted := false
+	succeededRetentionMinutes := int32(30)
+
+	testOpts := getDefaultOpts()
+	testOpts.Policies = []options.Policy{
+		{Namespaces: []string{"prod"}, TerminatingStateMinutes: &terminatingStateMinutes, TerminateEvicted: &terminateEvicted},
+		{Namespaces: []string{"ci-*"}, SucceededRetentionMinutes: &succeededRetentionMinutes},
+	}
+	resolver := newDetectorResolver(testOpts)
+
+	getDetector := func(name string) registeredDetector {
+		for _, d := range getRegisteredDetectors() {
+			if d.name == name {
+				return d
+			}
+		}
+
+		t.Fatalf("detector %s is not registered", name)
+		return registeredDetector{}
+	}
+
+	// succeeded detector is disabled globally but enabled by the ci-* policy
+	assert.Nil(t, getDetector("succeeded").factory(testOpts))
+	assert.NotNil(t, resolver.enabled(getDetector("succeeded")))
+	assert.Nil(t, resolver.enabled(getDetector("crashloop")))
+
+	assert.Nil(t, resolver.detectorFor(getDetector("evicted"), "prod"))
+	assert.NotNil(t, resolver.detectorFor(getDetector("evicted"), "default"))
+	assert.Nil(t, resolver.detectorFor(getDetector("succeeded"), "default"))
+	assert.NotNil(t, resolver.detectorFor(getDetector("succeeded"), "ci-1234"))
+
+	deletionTimestamp := &metav1.Time{Time: time.Now().Add(-20 * time.Minute)}
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "prod", DeletionTimestamp: deletionTimestamp}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default", DeletionTimestamp: deletionTimestamp}},
+	}
+
+	candidates := resolver.detectPods(getDetector("terminating"), pods, make(map[string]struct{}), nil)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, "pod-1", candidates[0].pod.Name)
+	assert.Equal(t, int32(10), candidates[0].opts.TerminatingStateMinutes)
+}
+
+func TestDetectorResolverSelectors(t *testing.T) {
+	podSelector := "team=batch"
+	testOpts := getDefaultOpts()
+	testOpts.Policies = []options.Policy{{Namespaces: []string{"prod"}, PodSelector: &podSelector}}
+	resolver := newDetectorResolver(testOpts)
+
+	deletionTimestamp := &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	pods := []v1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "prod", DeletionTimestamp: deletionTimestamp,
+			Labels: map[string]string{"team": "batch"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "prod", DeletionTimestamp: deletionTimestamp,
+			Labels: map[string]string{"team": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-3", Namespace: "default", DeletionTimestamp: deletionTimestamp,
+			Labels: map[string]string{"team": "web"}}},
+	}
+
+	for _, d := range getRegisteredDetectors() {
+		if d.name != "terminating" {
+			continue
+		}
+
+		candidates := resolver.detectPods(d, pods, make(map[string]struct{}), nil)
+		assert.Len(t, candidates, 2)
+		assert.Equal(t, "pod-1", candidates[0].pod.Name)
+		assert.Equal(t, "pod-3", candidates[1].pod.Name)
+	}
+}
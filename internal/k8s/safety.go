@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/metrics"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"go.uber.org/zap"
+)
+
+const (
+	limitAbortThreshold      = "abort-threshold"
+	limitMaxDeletionsPerRun  = "max-deletions-per-run"
+	limitNamespacePercentage = "max-namespace-deletion-percentage"
+)
+
+var (
+	// ErrRunAborted is returned by Run if the run is aborted by the safety limits
+	ErrRunAborted     = errors.New("run is aborted by the safety limits")
+	errLeftToNextRuns = errors.New("pod is left to the next runs by the safety limits")
+)
+
+// applySafetyLimits is the circuit breaker of a run. It returns ErrRunAborted if the number of the candidates
+// exceeds AbortCandidateThreshold, otherwise it returns the candidates which fit into
+// MaxNamespaceDeletionPercentage and MaxDeletionsPerRun, the rest is left to the next runs.
+func applySafetyLimits(candidates []candidate, pods *podCache, opts *options.KubePodTerminatorOptions,
+	logger *zap.Logger, cluster string) ([]candidate, error) {
+	if opts.AbortCandidateThreshold > 0 && len(candidates) > int(opts.AbortCandidateThreshold) {
+		metrics.SafetyLimitHits.WithLabelValues(cluster, limitAbortThreshold).Inc()
+		return nil, fmt.Errorf("%w: %d pods are matched, more than the abort threshold %d", ErrRunAborted,
+			len(candidates), opts.AbortCandidateThreshold)
+	}
+
+	if opts.MaxNamespaceDeletionPercentage > 0 {
+		limited, err := limitPerNamespace(candidates, pods, opts.MaxNamespaceDeletionPercentage)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(limited) < len(candidates) {
+			metrics.SafetyLimitHits.WithLabelValues(cluster, limitNamespacePercentage).Inc()
+			logger.Warn("some pods are left to the next runs by the namespace deletion percentage limit",
+				zap.Int32("maxNamespaceDeletionPercentage", opts.MaxNamespaceDeletionPercentage),
+				zap.Int("deferredPodCount", len(candidates)-len(limited)))
+		}
+
+		candidates = limited
+	}
+
+	if opts.MaxDeletionsPerRun > 0 && len(candidates) > int(opts.MaxDeletionsPerRun) {
+		metrics.SafetyLimitHits.WithLabelValues(cluster, limitMaxDeletionsPerRun).Inc()
+		logger.Warn("some pods are left to the next runs by the maximum deletions per run limit",
+			zap.Int32("maxDeletionsPerRun", opts.MaxDeletionsPerRun),
+			zap.Int("deferredPodCount", len(candidates)-int(opts.MaxDeletionsPerRun)))
+		candidates = candidates[:opts.MaxDeletionsPerRun]
+	}
+
+	return candidates, nil
+}
+
+// limitPerNamespace returns the candidates which fit into the percentage of the pods in their namespaces. At least
+// one pod per namespace is allowed, so that the namespaces with a few pods are not blocked forever.
+func limitPerNamespace(candidates []candidate, pods *podCache, percentage int32) ([]candidate, error) {
+	allPods, err := pods.get()
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]int)
+	for _, pod := range allPods {
+		totals[pod.Namespace]++
+	}
+
+	counts := make(map[string]int)
+	limited := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		allowed := totals[c.pod.Namespace] * int(percentage) / 100
+		if allowed < 1 {
+			allowed = 1
+		}
+
+		if counts[c.pod.Namespace] < allowed {
+			counts[c.pod.Namespace]++
+			limited = append(limited, c)
+		}
+	}
+
+	return limited, nil
+}
+
+// DeletionBudget spreads MaxDeletionsPerRun over the runs between two ticks in the long-running mode. The runs which
+// are triggered by the informer updates between the ticks would get a fresh limit each otherwise, and a flood of
+// updates would bypass the circuit breaker. The zero value is a full budget, and it is not safe for concurrent use.
+type DeletionBudget struct {
+	spent int32
+}
+
+// Options returns the options of the next run with the remaining budget as MaxDeletionsPerRun, nil if the budget is
+// exhausted. The options are returned as is if MaxDeletionsPerRun is disabled.
+func (b *DeletionBudget) Options(opts *options.KubePodTerminatorOptions) *options.KubePodTerminatorOptions {
+	if opts.MaxDeletionsPerRun <= 0 {
+		return opts
+	}
+
+	remaining := opts.MaxDeletionsPerRun - b.spent
+	if remaining <= 0 {
+		return nil
+	}
+
+	copied := *opts
+	copied.MaxDeletionsPerRun = remaining
+
+	return &copied
+}
+
+// Spend spends the budget for the pods of the report which any escalation step is sent for, even if it fails, and
+// for the pods which would be terminated in client side dry-run
+func (b *DeletionBudget) Spend(report *Report) {
+	for _, pod := range report.Pods {
+		if pod.Action != actionNone || pod.Result == PodDryRun {
+			b.spent++
+		}
+	}
+}
+
+// Reset refills the budget on each tick
+func (b *DeletionBudget) Reset() {
+	b.spent = 0
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestApplySafetyLimits(t *testing.T) {
+	cases := []struct {
+		caseName                                            string
+		maxDeletionsPerRun, maxNamespacePercentage, abortAt int32
+		expectedDefault, expectedProd                       int
+		shouldAbort                                         bool
+	}{
+		{"case1", 0, 0, 0, 10, 2, false},
+		{"case2", 5, 0, 0, 5, 0, false},
+		{"case3", 0, 20, 0, 4, 1, false},
+		{"case4", 3, 20, 0, 3, 0, false},
+		{"case5", 0, 0, 12, 10, 2, false},
+		{"case6", 0, 0, 11, 0, 0, true},
+	}
+
+	api := getFakeAPI()
+	var candidates []candidate
+	for _, ns := range []struct {
+		namespace string
+		podCount  int
+	}{{"default", 20}, {"prod", 2}} {
+		namespace := ns.namespace
+		for i := 0; i < ns.podCount; i++ {
+			pod, err := api.createEvictedPod(fmt.Sprintf("varnish-pod-%d", i), namespace)
+			assert.Nil(t, err)
+
+			// half of the pods in default namespace and all of the pods in prod namespace are candidates
+			if namespace == "prod" || i%2 == 0 {
+				candidates = append(candidates, candidate{pod: *pod, detector: newEvictedDetector(getDefaultOpts())})
+			}
+		}
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			testOpts := getDefaultOpts()
+			testOpts.MaxDeletionsPerRun = tc.maxDeletionsPerRun
+			testOpts.MaxNamespaceDeletionPercentage = tc.maxNamespacePercentage
+			testOpts.AbortCandidateThreshold = tc.abortAt
+
+			pods := newPodCache(context.Background(), NewAPIPodLister(api.ClientSet), testOpts, nil)
+			limited, err := applySafetyLimits(candidates, pods, testOpts, logging.GetLogger(), "")
+			assert.Equal(t, tc.shouldAbort, errors.Is(err, ErrRunAborted))
+
+			counts := make(map[string]int)
+			for _, c := range limited {
+				counts[c.pod.Namespace]++
+			}
+
+			assert.Equal(t, tc.expectedDefault, counts["default"])
+			assert.Equal(t, tc.expectedProd, counts["prod"])
+		})
+	}
+}
+
+func TestRunAbortedBySafetyLimits(t *testing.T) {
+	api := getFakeAPI()
+	testOpts := getDefaultOpts()
+	testOpts.AbortCandidateThreshold = 1
+
+	for _, name := range []string{"varnish-pod-1", "varnish-pod-2"} {
+		_, err := api.createEvictedPod(name, "default")
+		assert.Nil(t, err)
+	}
+
+	report, err := Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet),
+		record.NewFakeRecorder(100), nil, "")
+	assert.True(t, errors.Is(err, ErrRunAborted))
+	assert.Equal(t, RunAborted, report.Result)
+	assert.Len(t, report.Pods, 2)
+	for _, pod := range report.Pods {
+		assert.Equal(t, PodSkipped, pod.Result)
+	}
+
+	pods, err := api.ClientSet.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pods.Items, 2)
+}
+
+func TestDeletionBudget(t *testing.T) {
+	testOpts := getDefaultOpts()
+	var budget DeletionBudget
+	assert.Same(t, testOpts, budget.Options(testOpts))
+
+	testOpts.MaxDeletionsPerRun = 2
+	assert.Equal(t, int32(2), budget.Options(testOpts).MaxDeletionsPerRun)
+
+	budget.Spend(&Report{Pods: []PodReport{{Action: actionNone, Result: PodSkipped},
+		{Action: stepEvict, Result: PodDeferred}}})
+	assert.Equal(t, int32(1), budget.Options(testOpts).MaxDeletionsPerRun)
+	assert.Equal(t, int32(2), testOpts.MaxDeletionsPerRun)
+
+	// the failed deletions are sent to the kube-apiserver too, so they spend the budget like the terminated pods
+	budget.Spend(&Report{Pods: []PodReport{{Action: stepDelete, Result: PodFailed}}})
+	assert.Nil(t, budget.Options(testOpts))
+
+	budget.Reset()
+	budget.Spend(&Report{Pods: []PodReport{{Action: actionNone, Result: PodDryRun},
+		{Action: stepDelete, Result: PodTerminated}}})
+	assert.Nil(t, budget.Options(testOpts))
+
+	budget.Reset()
+	assert.Equal(t, int32(2), budget.Options(testOpts).MaxDeletionsPerRun)
+}
@@ -2,83 +2,362 @@ package k8s
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/metrics"
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
-// terminatePods does the real job, terminates the items in the v1.Pod channel with specified clientSet
-func terminatePods(podChannel chan v1.Pod, wg *sync.WaitGroup, clientSet kubernetes.Interface, logger *zap.Logger, gracePeriodSeconds int64) {
-	for pod := range podChannel {
-		if err := clientSet.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name,
-			metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds}); err != nil {
-			logger.Warn("an error occured while deleting pod", zap.String("name", pod.Name),
-				zap.String("error", err.Error()))
-			wg.Done()
-			continue
-		}
+var (
+	// ErrShuttingDown is returned by Run if the run is interrupted since ctx is done
+	ErrShuttingDown = errors.New("kube-pod-terminator is shutting down")
+	// ErrClusterUnreachable is returned by Run if the namespaces or the pods of the cluster can not be listed
+	ErrClusterUnreachable = errors.New("cluster is unreachable")
+	// ErrPartialFailure is returned by Run if some of the candidates can not be terminated
+	ErrPartialFailure = errors.New("some pods could not be terminated")
+)
 
-		logger.Info("pod successfully terminated", zap.String("name", pod.Name), zap.String("namespace", pod.Namespace))
+// candidate is a pod which is matched by a Detector and waiting to be terminated with the effective options of
+// its namespace
+type candidate struct {
+	pod      v1.Pod
+	detector Detector
+	opts     *options.KubePodTerminatorOptions
+	// namespace is the namespace of the pod which is fetched by the annotationFilter, nil if it is not found
+	namespace *v1.Namespace
+}
+
+// terminator terminates the candidates of a run. Once ctx is done, candidates which are not started yet are
+// skipped, while the in-flight deletions go on with deleteCtx until it is cancelled after the drain timeout.
+type terminator struct {
+	ctx       context.Context
+	deleteCtx context.Context
+	clientSet kubernetes.Interface
+	recorder  record.EventRecorder
+	limiter   flowcontrol.RateLimiter
+	logger    *zap.Logger
+	opts      *options.KubePodTerminatorOptions
+	cluster   string
+	reports   podReports
+}
+
+// terminatePods does the real job, terminates the items in the candidate channel and records the events of the
+// terminated pods. Several terminatePods goroutines can consume the same channel, limiter is shared between them to
+// throttle the deletion requests, a nil limiter means no throttling.
+func (t *terminator) terminatePods(podChannel <-chan candidate, wg *sync.WaitGroup) {
+	for c := range podChannel {
+		t.reports.add(t.terminatePod(c))
 		wg.Done()
 	}
 }
 
-// addPodsToChannel adds items of v1.Pod slice to specified v1.Pod channel
-func addPodsToChannel(podChannel chan v1.Pod, wg *sync.WaitGroup, podSlice []v1.Pod, state string, logger *zap.Logger) {
-	for _, pod := range podSlice {
-		logger.Info("adding pod to podChannel channel", zap.String("name", pod.Name),
-			zap.String("namespace", pod.Namespace), zap.String("state", state))
-		podChannel <- pod
+// terminatePod terminates a single candidate once limiter permits it and returns its report
+func (t *terminator) terminatePod(c candidate) PodReport {
+	podLogger := t.logger.With(zap.String("name", c.pod.Name), zap.String("namespace", c.pod.Namespace),
+		zap.String("state", c.detector.Name()))
+
+	if t.opts.DryRun == options.DryRunClient {
+		podLogger.Info("pod would be terminated, skipping since client side dry-run is enabled",
+			zap.String("reason", c.detector.Reason()))
+		return newPodReport(c, actionNone, PodDryRun, nil)
+	}
+
+	if t.ctx.Err() != nil || waitForLimiter(t.ctx, t.limiter) != nil {
+		podLogger.Info("pod is not terminated since kube-pod-terminator is shutting down")
+		return newPodReport(c, actionNone, PodSkipped, ErrShuttingDown)
+	}
+
+	d, err := deletePod(t.deleteCtx, t.clientSet, t.limiter, c.pod, c.opts, podLogger)
+	if err != nil {
+		metrics.DeletionErrors.WithLabelValues(t.cluster, c.pod.Namespace, c.detector.Name(),
+			getErrorReason(err)).Inc()
+		if errors.Is(err, errEvictionDeferred) {
+			podLogger.Info("pod is not terminated", zap.String("reason", err.Error()))
+			return newPodReport(c, d.step, PodDeferred, err)
+		}
+
+		podLogger.Warn("an error occured while deleting pod", zap.String("error", err.Error()))
+		return newPodReport(c, d.step, PodFailed, err)
+	}
+
+	if t.opts.DryRun == options.DryRunServer {
+		podLogger.Info("pod would be terminated, deletion is validated by the server since server side dry-run is enabled",
+			zap.String("reason", c.detector.Reason()))
+		return newPodReport(c, d.step, PodDryRun, nil)
+	}
+
+	metrics.PodsTerminated.WithLabelValues(t.cluster, c.pod.Namespace, string(c.pod.Status.Phase),
+		c.detector.Name()).Inc()
+	recordTerminationEvents(t.recorder, c, d.gracePeriodSeconds)
+	podLogger.Info("pod successfully terminated")
+
+	return newPodReport(c, d.step, PodTerminated, nil)
+}
+
+// addPodsToChannel adds items of candidate slice to specified candidate channel until ctx is done, returns the
+// number of the added items
+func addPodsToChannel(ctx context.Context, podChannel chan<- candidate, wg *sync.WaitGroup, candidates []candidate,
+	logger *zap.Logger) int {
+	for i, c := range candidates {
+		logger.Info("adding pod to podChannel channel", zap.String("name", c.pod.Name),
+			zap.String("namespace", c.pod.Namespace), zap.String("state", c.detector.Name()),
+			zap.String("reason", c.detector.Reason()))
 		wg.Add(1)
+		select {
+		case podChannel <- c:
+		case <-ctx.Done():
+			wg.Done()
+			return i
+		}
 	}
+
+	return len(candidates)
 }
 
-// Run operates the business logic, fetches the terminating and evicted pods and terminates them
-func Run(opts *options.KubePodTerminatorOptions, clientSet kubernetes.Interface, apiServer string) {
-	logger := logging.GetLogger().With(zap.String("apiServer", apiServer))
-	podChannel := make(chan v1.Pod, 50)
+// NewDeleteRateLimiter returns the token bucket which throttles the deletion requests of the options, nil if it is
+// disabled. It is meant to be shared between the runs of a cluster, so that each run does not start with a full burst.
+func NewDeleteRateLimiter(opts *options.KubePodTerminatorOptions) flowcontrol.RateLimiter {
+	if opts.DeleteQPS <= 0 {
+		return nil
+	}
+
+	return flowcontrol.NewTokenBucketRateLimiter(opts.DeleteQPS, max(opts.DeleteBurst, 1))
+}
+
+// newDeleteContext returns the context of the in-flight deletions, which outlives ctx by the drain timeout
+func newDeleteContext(ctx context.Context, opts *options.KubePodTerminatorOptions) (context.Context,
+	context.CancelFunc) {
+	deleteCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		drain := time.NewTimer(time.Duration(opts.DrainTimeoutSeconds) * time.Second)
+		defer drain.Stop()
+
+		select {
+		case <-drain.C:
+			cancel()
+		case <-deleteCtx.Done():
+		}
+	})
+
+	return deleteCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// terminateCandidates terminates the candidates with a pool of opts.Workers goroutines which are throttled by limiter,
+// waits for all of them and returns the reports of the candidates
+func terminateCandidates(ctx context.Context, candidates []candidate, clientSet kubernetes.Interface,
+	recorder record.EventRecorder, limiter flowcontrol.RateLimiter, logger *zap.Logger,
+	opts *options.KubePodTerminatorOptions, cluster string) []PodReport {
+	deleteCtx, cancel := newDeleteContext(ctx, opts)
+	defer cancel()
+
+	t := &terminator{ctx: ctx, deleteCtx: deleteCtx, clientSet: clientSet, recorder: recorder,
+		limiter: limiter, logger: logger, opts: opts, cluster: cluster}
+
 	var wg sync.WaitGroup
+	workers := max(opts.Workers, 1)
+	podChannel := make(chan candidate, workers)
+	for i := 0; i < workers; i++ {
+		go t.terminatePods(podChannel, &wg)
+	}
 
-	terminatingPods, err := getTerminatingPods(clientSet, opts.Namespace, opts.TerminatingStateMinutes)
-	if err != nil {
-		logger.Warn("an error occurred while getting terminating pods, skipping execution", zap.Error(err))
-		return
+	added := addPodsToChannel(ctx, podChannel, &wg, candidates, logger)
+	close(podChannel)
+	wg.Wait()
+
+	return append(t.reports.reports, getSkippedReports(candidates[added:], nil, ErrShuttingDown)...)
+}
+
+// recordRun updates the metrics of a detection run which is started at start, result is one of RunSuccess,
+// RunPartialFailure, RunFailure or RunAborted. Only RunSuccess updates the timestamp of the last successful run.
+func recordRun(cluster string, start time.Time, result string) {
+	metrics.RunDuration.WithLabelValues(cluster).Observe(time.Since(start).Seconds())
+	metrics.Runs.WithLabelValues(cluster, result).Inc()
+	if result == RunSuccess {
+		metrics.LastSuccessfulRun.WithLabelValues(cluster).SetToCurrentTime()
 	}
+}
 
-	if len(terminatingPods) > 0 {
-		logger.Info("found pods", zap.String("state", "terminating"), zap.Int("podCount", len(terminatingPods)))
-		log.Println(cap(podChannel))
-		log.Println(len(podChannel))
-		addPodsToChannel(podChannel, &wg, terminatingPods, "terminating", logger)
-	} else {
-		logger.Info("no pod found, skipping execution", zap.String("state", "terminating"))
+// newListContext returns the context of the list calls of a run, which times out after the list timeout unless it
+// is disabled
+func newListContext(ctx context.Context, opts *options.KubePodTerminatorOptions) (context.Context,
+	context.CancelFunc) {
+	if opts.ListTimeoutSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Duration(opts.ListTimeoutSeconds)*time.Second)
+}
+
+// podCache lists the target pods once per run and shares them between the detectors and the safety limits
+type podCache struct {
+	ctx                context.Context
+	podLister          PodLister
+	opts               *options.KubePodTerminatorOptions
+	selectedNamespaces map[string]struct{}
+	pods               []v1.Pod
+	listed             bool
+}
+
+// newPodCache returns an empty podCache for the target pods of the options in the selected namespaces
+func newPodCache(ctx context.Context, podLister PodLister, opts *options.KubePodTerminatorOptions,
+	selectedNamespaces map[string]struct{}) *podCache {
+	return &podCache{ctx: ctx, podLister: podLister, opts: opts, selectedNamespaces: selectedNamespaces}
+}
+
+// get returns the target pods, they are listed with podLister in each of the list namespaces on the first call
+func (c *podCache) get() ([]v1.Pod, error) {
+	if c.listed {
+		return c.pods, nil
 	}
 
-	if opts.TerminateEvicted {
-		evictedPods, err := getEvictedPods(clientSet, opts.Namespace)
+	var pods []v1.Pod
+	for _, namespace := range getListNamespaces(c.opts) {
+		namespacePods, err := c.podLister.ListPods(c.ctx, namespace, getListOptions(c.opts))
 		if err != nil {
-			logger.Warn("an error occurred while getting evicted pods, skipping execution", zap.Error(err))
-			return
+			return nil, err
 		}
 
-		if len(evictedPods) > 0 {
-			logger.Info("found pods", zap.String("state", "evicted"), zap.Int("podCount", len(evictedPods)))
-			addPodsToChannel(podChannel, &wg, evictedPods, "evicted", logger)
-		} else {
-			logger.Info("no pod found, skipping execution", zap.String("state", "evicted"))
+		pods = append(pods, namespacePods...)
+	}
+
+	c.pods, c.listed = filterPodsByNamespace(pods, c.selectedNamespaces), true
+
+	return c.pods, nil
+}
+
+// detectCandidates evaluates the target pods with the registered detectors and returns the matched ones
+func detectCandidates(ctx context.Context, clientSet kubernetes.Interface, pods *podCache,
+	opts *options.KubePodTerminatorOptions, logger *zap.Logger, cluster string) ([]candidate, error) {
+	var candidates []candidate
+	matched := make(map[string]struct{})
+	resolver := newDetectorResolver(opts)
+	annotations := newAnnotationFilter(ctx, clientSet)
+	for _, d := range getRegisteredDetectors() {
+		detector := resolver.enabled(d)
+		if detector == nil {
+			logger.Info("detector is disabled by the options, skipping", zap.String("state", d.name))
+			continue
 		}
-	} else {
-		logger.Info("will not terminate evicted pods since --terminateEvicted=false argument passed")
+
+		podList, err := pods.get()
+		if err != nil {
+			return nil, err
+		}
+
+		targets := annotations.filter(d, resolver.detectPods(d, podList, matched, annotations.override), matched, logger)
+		if len(targets) == 0 {
+			logger.Info("no pod found, skipping execution", zap.String("state", detector.Name()))
+			continue
+		}
+
+		logger.Info("found pods", zap.String("state", detector.Name()), zap.String("reason", detector.Reason()),
+			zap.Int("podCount", len(targets)))
+		for _, c := range targets {
+			metrics.PodsFound.WithLabelValues(cluster, c.pod.Namespace, string(c.pod.Status.Phase),
+				c.detector.Name()).Inc()
+		}
+
+		candidates = append(candidates, targets...)
 	}
 
-	close(podChannel)
+	return candidates, nil
+}
 
-	go terminatePods(podChannel, &wg, clientSet, logger, opts.GracePeriodSeconds)
-	wg.Wait()
+// Run operates the business logic, fetches the pods with podLister, evaluates them with the registered detectors
+// and terminates the matched ones within the safety limits. The deletion requests are throttled by limiter, which is
+// shared between the runs of the cluster, a nil limiter means no throttling. Once ctx is done, no more pods are terminated and the
+// in-flight deletions are given the drain timeout to finish. It returns the report of the run, and an error which
+// wraps one of ErrClusterUnreachable, ErrRunAborted, ErrShuttingDown or ErrPartialFailure if the run is not
+// completely successful.
+func Run(ctx context.Context, opts *options.KubePodTerminatorOptions, clientSet kubernetes.Interface,
+	podLister PodLister, recorder record.EventRecorder, limiter flowcontrol.RateLimiter,
+	cluster string) (*Report, error) {
+	logger := logging.GetLogger().With(zap.String("cluster", cluster))
+	start := time.Now()
+	report := &Report{Cluster: cluster, Pods: []PodReport{}}
+	listCtx, cancel := newListContext(ctx, opts)
+	defer cancel()
+
+	selectedNamespaces, err := getSelectedNamespaces(listCtx, clientSet, opts)
+	if err != nil {
+		logger.Warn("an error occurred while getting namespaces, skipping execution", zap.Error(err))
+		return failRun(ctx, report, start, err)
+	}
+
+	pods := newPodCache(listCtx, podLister, opts, selectedNamespaces)
+	candidates, err := detectCandidates(listCtx, clientSet, pods, opts, logger, cluster)
+	if err != nil {
+		logger.Warn("an error occurred while getting pods, skipping execution", zap.Error(err))
+		return failRun(ctx, report, start, err)
+	}
+
+	limited, err := applySafetyLimits(candidates, pods, opts, logger, cluster)
+	if err != nil {
+		report.Pods = append(report.Pods, getSkippedReports(candidates, nil, err)...)
+		if errors.Is(err, ErrRunAborted) {
+			logger.Error("run is aborted by the safety limits, no pod is terminated", zap.Error(err))
+			recordRun(cluster, start, RunAborted)
+			return report.finish(RunAborted, err), err
+		}
+
+		logger.Warn("an error occurred while applying safety limits, skipping execution", zap.Error(err))
+		return failRun(ctx, report, start, err)
+	}
+
+	report.Pods = append(report.Pods, getSkippedReports(candidates, limited, errLeftToNextRuns)...)
+	report.Pods = append(report.Pods, terminateCandidates(ctx, limited, clientSet, recorder, limiter, logger, opts,
+		cluster)...)
+	if ctx.Err() != nil {
+		logger.Warn("run is interrupted since kube-pod-terminator is shutting down")
+		recordRun(cluster, start, RunFailure)
+		return report.finish(RunFailure, ErrShuttingDown), ErrShuttingDown
+	}
+
+	result := RunSuccess
+	if err = report.getPartialFailure(); err != nil {
+		result = RunPartialFailure
+	}
+
+	recordRun(cluster, start, result)
+	report.finish(result, err)
+
+	// only the pods which are reported as dry-run would be terminated, e.g. the server side dry-run can reject some
+	if opts.IsDryRun() {
+		dryRunPods := make([]PodReport, 0, len(report.Pods))
+		for _, pod := range report.Pods {
+			if pod.Result == PodDryRun {
+				dryRunPods = append(dryRunPods, pod)
+			}
+		}
+
+		logger.Info("dry-run report of the pods which would be terminated", zap.String("dryRun", opts.DryRun),
+			zap.Int("podCount", len(dryRunPods)), zap.Any("pods", dryRunPods))
+	}
+
+	return report, err
+}
+
+// failRun records the run which is failed while listing the namespaces or the pods with err. The failure is caused
+// by the shutdown if ctx is done, otherwise the cluster is treated as unreachable.
+func failRun(ctx context.Context, report *Report, start time.Time, err error) (*Report, error) {
+	recordRun(report.Cluster, start, RunFailure)
+	if ctx.Err() != nil {
+		err = ErrShuttingDown
+	} else {
+		err = fmt.Errorf("%w: %w", ErrClusterUnreachable, err)
+	}
+
+	return report.finish(RunFailure, err), err
 }
@@ -2,6 +2,8 @@ package k8s
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -9,13 +11,19 @@ import (
 	"time"
 
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/metrics"
 	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 type FakeAPI struct {
@@ -25,16 +33,25 @@ type FakeAPI struct {
 
 func getDefaultOpts() *options.KubePodTerminatorOptions {
 	return &options.KubePodTerminatorOptions{
-		InCluster:               true,
-		KubeConfigPaths:         filepath.Join(os.Getenv("HOME"), ".kube", "config"),
-		Namespace:               "all",
-		TickerIntervalMinutes:   5,
-		GracePeriodSeconds:      30,
-		TerminateEvicted:        true,
-		TerminatingStateMinutes: 30,
-		OneShot:                 false,
-		BannerFilePath:          "",
-		VerboseLog:              false,
+		InCluster:                  true,
+		KubeConfigPaths:            filepath.Join(os.Getenv("HOME"), ".kube", "config"),
+		Namespace:                  "all",
+		TickerIntervalMinutes:      5,
+		GracePeriodSeconds:         30,
+		TerminateEvicted:           true,
+		TerminatingStateMinutes:    30,
+		TerminateCrashLoop:         false,
+		CrashLoopStateMinutes:      60,
+		CrashLoopRestartCount:      0,
+		TerminateImagePullErrors:   false,
+		ImagePullErrorStateMinutes: 30,
+		SucceededRetentionMinutes:  0,
+		FailedRetentionMinutes:     0,
+		ListTimeoutSeconds:         60,
+		OneShot:                    false,
+		BannerFilePath:             "",
+		VerboseLog:                 false,
+		DryRun:                     options.DryRunNone,
 	}
 }
 
@@ -163,7 +180,7 @@ func TestRunNoTargetTerminatingPods(t *testing.T) {
 		})
 	}
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunDoNotTerminateEvictedPods(t *testing.T) {
@@ -184,7 +201,7 @@ func TestRunDoNotTerminateEvictedPods(t *testing.T) {
 	assert.NotNil(t, pod2)
 	time.Sleep(2 * time.Second)
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunEvictedPodsAllNamespaces(t *testing.T) {
@@ -221,7 +238,7 @@ func TestRunEvictedPodsAllNamespaces(t *testing.T) {
 		})
 	}
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunEvictedPodsAllNamespacesOneShot(t *testing.T) {
@@ -259,7 +276,7 @@ func TestRunEvictedPodsAllNamespacesOneShot(t *testing.T) {
 		})
 	}
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunEvictedPodsSingleNamespace(t *testing.T) {
@@ -295,11 +312,11 @@ func TestRunEvictedPodsSingleNamespace(t *testing.T) {
 		})
 	}
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunBrokenApiCall(t *testing.T) {
-	restConfig, err := GetConfig("../../test/kubeconfig", false)
+	restConfig, err := GetConfig("../../test/kubeconfig", "", false, 0, 0)
 	assert.Nil(t, err)
 	assert.NotNil(t, restConfig)
 
@@ -310,7 +327,7 @@ func TestRunBrokenApiCall(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, clientSet)
 
-	Run(testOpts, clientSet, "")
+	Run(context.Background(), testOpts, clientSet, NewAPIPodLister(clientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunTerminatingPodsAllNamespaces(t *testing.T) {
@@ -350,7 +367,7 @@ func TestRunTerminatingPodsAllNamespaces(t *testing.T) {
 		})
 	}
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestRunTerminatingPodsSingleNamespace(t *testing.T) {
@@ -384,11 +401,11 @@ func TestRunTerminatingPodsSingleNamespace(t *testing.T) {
 		})
 	}
 
-	Run(testOpts, api.ClientSet, "")
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
 }
 
 func TestGetClientSet(t *testing.T) {
-	restConfig, err := GetConfig("../../test/kubeconfig", false)
+	restConfig, err := GetConfig("../../test/kubeconfig", "", false, 0, 0)
 	assert.Nil(t, err)
 	assert.NotNil(t, restConfig)
 
@@ -396,11 +413,65 @@ func TestGetClientSet(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, clientSet)
 
-	restConfig, err = GetConfig("../../test/broken_kubeconfig", false)
+	restConfig, err = GetConfig("../../test/kubeconfig", "", false, 20, 40)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(20), restConfig.QPS)
+	assert.Equal(t, 40, restConfig.Burst)
+
+	restConfig, err = GetConfig("../../test/broken_kubeconfig", "", false, 0, 0)
 	assert.NotNil(t, err)
 	assert.Nil(t, restConfig)
 }
 
+func TestGetConfigContext(t *testing.T) {
+	restConfig, err := GetConfig("../../test/multi_context_kubeconfig", "", false, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://192.168.49.2:8443", restConfig.Host)
+
+	restConfig, err = GetConfig("../../test/multi_context_kubeconfig", "production", false, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://192.168.49.4:8443", restConfig.Host)
+
+	_, err = GetConfig("../../test/multi_context_kubeconfig", "development", false, 0, 0)
+	assert.NotNil(t, err)
+}
+
+func TestGetContexts(t *testing.T) {
+	cases := []struct {
+		caseName, kubeConfigPath, contexts string
+		expected                           []string
+		shouldFail                         bool
+	}{
+		{"case1", "../../test/multi_context_kubeconfig", "all", []string{"minikube", "production", "staging"}, false},
+		{"case2", "../../test/multi_context_kubeconfig", "ALL", []string{"minikube", "production", "staging"}, false},
+		{"case3", "../../test/multi_context_kubeconfig", "staging, production", []string{"staging", "production"},
+			false},
+		{"case4", "../../test/multi_context_kubeconfig", "staging,development", nil, true},
+		{"case5", "../../test/nonexistent_kubeconfig", "all", nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			contexts, err := GetContexts(tc.kubeConfigPath, tc.contexts)
+			assert.Equal(t, tc.shouldFail, err != nil)
+			assert.Equal(t, tc.expected, contexts)
+		})
+	}
+}
+
+func TestCheckClientSet(t *testing.T) {
+	api := getFakeAPI()
+	assert.Nil(t, CheckClientSet(api.ClientSet))
+
+	restConfig, err := GetConfig("../../test/kubeconfig", "", false, 0, 0)
+	assert.Nil(t, err)
+	restConfig.Timeout = time.Second
+
+	clientSet, err := GetClientSet(restConfig)
+	assert.Nil(t, err)
+	assert.NotNil(t, CheckClientSet(clientSet))
+}
+
 func TestTerminatePodsWithoutCreating(t *testing.T) {
 	api := getFakeAPI()
 	assert.NotNil(t, api)
@@ -409,11 +480,13 @@ func TestTerminatePodsWithoutCreating(t *testing.T) {
 	testOpts := getDefaultOpts()
 
 	wg.Add(1)
-	podChannel := make(chan v1.Pod, 10)
-	podChannel <- v1.Pod{}
+	podChannel := make(chan candidate, 10)
+	podChannel <- candidate{pod: v1.Pod{}, detector: newTerminatingDetector(testOpts), opts: testOpts}
 	/*pod, _ := api.createTerminatingPod("demo-pod", "default", nil)
 	podChannel <- *pod*/
-	go terminatePods(podChannel, &wg, api.ClientSet, logging.GetLogger(), testOpts.GracePeriodSeconds)
+	terminator := &terminator{ctx: context.Background(), deleteCtx: context.Background(), clientSet: api.ClientSet,
+		recorder: record.NewFakeRecorder(100), logger: logging.GetLogger(), opts: testOpts, cluster: ""}
+	go terminator.terminatePods(podChannel, &wg)
 	wg.Wait()
 }
 
@@ -425,9 +498,324 @@ func TestTerminatePods(t *testing.T) {
 	testOpts := getDefaultOpts()
 
 	wg.Add(1)
-	podChannel := make(chan v1.Pod, 10)
+	podChannel := make(chan candidate, 10)
 	pod, _ := api.createTerminatingPod("demo-pod", "default", nil)
-	podChannel <- *pod
-	go terminatePods(podChannel, &wg, api.ClientSet, logging.GetLogger(), testOpts.GracePeriodSeconds)
+	podChannel <- candidate{pod: *pod, detector: newTerminatingDetector(testOpts), opts: testOpts}
+	close(podChannel)
+	terminator := &terminator{ctx: context.Background(), deleteCtx: context.Background(), clientSet: api.ClientSet,
+		recorder: record.NewFakeRecorder(100), logger: logging.GetLogger(), opts: testOpts, cluster: "https://terminate-pods"}
+	go terminator.terminatePods(podChannel, &wg)
 	wg.Wait()
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PodsTerminated.WithLabelValues("https://terminate-pods",
+		"default", string(pod.Status.Phase), "terminating")))
+}
+
+func TestRunDryRun(t *testing.T) {
+	cases := []struct {
+		caseName, dryRun string
+		expectedDeletes  int
+	}{
+		{"case1", options.DryRunClient, 0},
+		{"case2", options.DryRunServer, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			assert.NotNil(t, api)
+
+			_, _ = api.createNamespace("default")
+			testOpts := getDefaultOpts()
+			testOpts.Namespace = "default"
+			testOpts.DryRun = tc.dryRun
+
+			pod, err := api.createEvictedPod("varnish-pod-1", "default")
+			assert.Nil(t, err)
+			assert.NotNil(t, pod)
+
+			Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
+
+			// fake clientset does not respect DryRun, so check the sent deletion requests instead of the pods
+			var deletes []k8stesting.DeleteActionImpl
+			for _, action := range api.ClientSet.(*fake.Clientset).Actions() {
+				if deleteAction, ok := action.(k8stesting.DeleteActionImpl); ok {
+					deletes = append(deletes, deleteAction)
+				}
+			}
+
+			assert.Len(t, deletes, tc.expectedDeletes)
+			for _, deleteAction := range deletes {
+				assert.Equal(t, []string{metav1.DryRunAll}, deleteAction.GetDeleteOptions().DryRun)
+			}
+		})
+	}
+}
+
+func TestRunCompletedPods(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default"
+	testOpts.SucceededRetentionMinutes = 60
+
+	pod := getCompletedPod(v1.PodSucceeded, time.Now().Add(-90*time.Minute))
+	pod.Name = "varnish-pod-1"
+	pod.Namespace = "default"
+	_, err := api.ClientSet.CoreV1().Pods("default").Create(context.Background(), &pod, metav1.CreateOptions{})
+	assert.Nil(t, err)
+
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
+
+	var fieldSelectors []string
+	for _, action := range api.ClientSet.(*fake.Clientset).Actions() {
+		if listAction, ok := action.(k8stesting.ListActionImpl); ok {
+			fieldSelectors = append(fieldSelectors, listAction.GetListRestrictions().Fields.String())
+		}
+	}
+
+	// the terminating detector needs the unfiltered list, so the succeeded pods are filtered from it locally
+	assert.Equal(t, []string{""}, fieldSelectors)
+
+	_, err = api.ClientSet.CoreV1().Pods("default").Get(context.Background(), "varnish-pod-1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestRunPodSelector(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default"
+	testOpts.PodSelector = "team=batch"
+	testOpts.PodFieldSelector = "spec.nodeName=node-1"
+	testOpts.SucceededRetentionMinutes = 60
+
+	for name, team := range map[string]string{"varnish-pod-1": "batch", "varnish-pod-2": "web"} {
+		pod, err := api.createEvictedPod(name, "default")
+		assert.Nil(t, err)
+
+		pod.Labels = map[string]string{"team": team}
+		pod.Spec.NodeName = "node-1"
+		_, err = api.ClientSet.CoreV1().Pods("default").Update(context.Background(), pod, metav1.UpdateOptions{})
+		assert.Nil(t, err)
+	}
+
+	Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil, "")
+
+	var labelSelectors, fieldSelectors []string
+	for _, action := range api.ClientSet.(*fake.Clientset).Actions() {
+		if listAction, ok := action.(k8stesting.ListActionImpl); ok {
+			labelSelectors = append(labelSelectors, listAction.GetListRestrictions().Labels.String())
+			fieldSelectors = append(fieldSelectors, listAction.GetListRestrictions().Fields.String())
+		}
+	}
+
+	assert.Equal(t, []string{"team=batch"}, labelSelectors)
+	assert.Equal(t, []string{"spec.nodeName=node-1"}, fieldSelectors)
+
+	_, err := api.ClientSet.CoreV1().Pods("default").Get(context.Background(), "varnish-pod-1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	_, err = api.ClientSet.CoreV1().Pods("default").Get(context.Background(), "varnish-pod-2", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestPodCache(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	for name, phase := range map[string]v1.PodPhase{"varnish-pod-1": v1.PodSucceeded, "varnish-pod-2": v1.PodRunning} {
+		pod := getCompletedPod(phase, time.Now())
+		pod.Name = name
+		pod.Namespace = "default"
+		_, err := api.ClientSet.CoreV1().Pods("default").Create(context.Background(), &pod, metav1.CreateOptions{})
+		assert.Nil(t, err)
+	}
+
+	testOpts := getDefaultOpts()
+	testOpts.PodFieldSelector = "spec.nodeName=node-1"
+	pods := newPodCache(context.Background(), NewAPIPodLister(api.ClientSet), testOpts, nil)
+	for i := 0; i < 2; i++ {
+		allPods, err := pods.get()
+		assert.Nil(t, err)
+		assert.Len(t, allPods, 2)
+	}
+
+	// the pods are listed once per run and only the field selector of the options is pushed into the list call
+	var fieldSelectors []string
+	for _, action := range api.ClientSet.(*fake.Clientset).Actions() {
+		if listAction, ok := action.(k8stesting.ListActionImpl); ok {
+			fieldSelectors = append(fieldSelectors, listAction.GetListRestrictions().Fields.String())
+		}
+	}
+
+	assert.Equal(t, []string{"spec.nodeName=node-1"}, fieldSelectors)
+}
+
+func TestRunWorkerPool(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default"
+	testOpts.Workers = 10
+
+	// more pods than the former fixed channel capacity of 50 must not block the run
+	for i := 0; i < 120; i++ {
+		_, err := api.createEvictedPod(fmt.Sprintf("varnish-pod-%d", i), "default")
+		assert.Nil(t, err)
+	}
+
+	report, err := Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet),
+		&record.FakeRecorder{}, nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, RunSuccess, report.Result)
+	assert.Len(t, report.Pods, 120)
+	for _, pod := range report.Pods {
+		assert.Equal(t, PodTerminated, pod.Result)
+	}
+
+	pods, err := api.ClientSet.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, pods.Items, 0)
+}
+
+func TestNewDeleteRateLimiter(t *testing.T) {
+	testOpts := getDefaultOpts()
+	assert.Nil(t, NewDeleteRateLimiter(testOpts))
+
+	testOpts.DeleteQPS = 0.1
+	testOpts.DeleteBurst = 2
+	limiter := NewDeleteRateLimiter(testOpts)
+	assert.NotNil(t, limiter)
+	assert.True(t, limiter.TryAccept())
+	assert.True(t, limiter.TryAccept())
+	assert.False(t, limiter.TryAccept())
+
+	testOpts.DeleteBurst = 0
+	limiter = NewDeleteRateLimiter(testOpts)
+	assert.True(t, limiter.TryAccept())
+	assert.False(t, limiter.TryAccept())
+}
+
+func TestRunSharedRateLimiter(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default"
+	testOpts.DeleteQPS = 0.001
+	limiter := NewDeleteRateLimiter(testOpts)
+
+	_, err := api.createEvictedPod("varnish-pod-1", "default")
+	assert.Nil(t, err)
+	report, err := Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet),
+		record.NewFakeRecorder(100), limiter, "")
+	assert.Nil(t, err)
+	assert.Equal(t, PodTerminated, report.Pods[0].Result)
+
+	// the burst is spent by the previous run, so the next run does not start with a full burst
+	_, err = api.createEvictedPod("varnish-pod-2", "default")
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	report, err = Run(ctx, testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100),
+		limiter, "")
+	assert.True(t, errors.Is(err, ErrShuttingDown))
+	assert.Equal(t, PodSkipped, report.Pods[0].Result)
+}
+
+func TestRunShutdown(t *testing.T) {
+	api := getFakeAPI()
+	assert.NotNil(t, api)
+
+	testOpts := getDefaultOpts()
+	testOpts.Namespace = "default"
+
+	_, err := api.createEvictedPod("varnish-pod-1", "default")
+	assert.Nil(t, err)
+
+	// pods are still listed on shutdown but none of them is terminated anymore
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := Run(ctx, testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet), record.NewFakeRecorder(100), nil,
+		"https://shutdown")
+	assert.True(t, errors.Is(err, ErrShuttingDown))
+	assert.Equal(t, RunFailure, report.Result)
+	assert.Len(t, report.Pods, 1)
+	assert.Equal(t, PodSkipped, report.Pods[0].Result)
+
+	_, err = api.ClientSet.CoreV1().Pods("default").Get(context.Background(), "varnish-pod-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Runs.WithLabelValues("https://shutdown", RunFailure)))
+}
+
+func TestRunErrors(t *testing.T) {
+	cases := []struct {
+		caseName, verb string
+		expected       error
+		expectedResult string
+	}{
+		{"case1", "list", ErrClusterUnreachable, RunFailure},
+		{"case2", "delete", ErrPartialFailure, RunPartialFailure},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			api := getFakeAPI()
+			api.ClientSet.(*fake.Clientset).PrependReactor(tc.verb, "pods",
+				func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(v1.Resource("pods"), "", errors.New("denied"))
+				})
+
+			testOpts := getDefaultOpts()
+			testOpts.Namespace = "default"
+			_, err := api.createEvictedPod("varnish-pod-1", "default")
+			assert.Nil(t, err)
+
+			report, err := Run(context.Background(), testOpts, api.ClientSet, NewAPIPodLister(api.ClientSet),
+				record.NewFakeRecorder(100), nil, "https://"+tc.verb)
+			assert.True(t, errors.Is(err, tc.expected))
+			assert.Equal(t, err.Error(), report.Error)
+			assert.Equal(t, tc.expectedResult, report.Result)
+			assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Runs.WithLabelValues("https://"+tc.verb,
+				tc.expectedResult)))
+			assert.Equal(t, float64(0), testutil.ToFloat64(metrics.LastSuccessfulRun.WithLabelValues("https://"+tc.verb)))
+		})
+	}
+}
+
+func TestNewDeleteContext(t *testing.T) {
+	testOpts := getDefaultOpts()
+	testOpts.DrainTimeoutSeconds = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deleteCtx, cancelDelete := newDeleteContext(ctx, testOpts)
+	defer cancelDelete()
+
+	// in-flight deletions go on for the drain timeout after the shutdown
+	cancel()
+	assert.Nil(t, deleteCtx.Err())
+
+	select {
+	case <-deleteCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the delete context to be cancelled after the drain timeout")
+	}
+}
+
+func TestNewListContext(t *testing.T) {
+	testOpts := getDefaultOpts()
+	testOpts.ListTimeoutSeconds = 0
+	ctx, cancel := newListContext(context.Background(), testOpts)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+
+	testOpts.ListTimeoutSeconds = 60
+	ctx, cancel = newListContext(context.Background(), testOpts)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.True(t, ok)
 }
@@ -1,21 +1,20 @@
 package k8s
 
 import (
-	"context"
 	"fmt"
+	"sort"
 	"strings"
-	"time"
 
-	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// GetConfig gets parameters to generate rest.Config and returns it
-func GetConfig(kubeConfigPath string, inCluster bool) (*rest.Config, error) {
+// GetConfig gets parameters to generate rest.Config and returns it. kubeConfigPath can also be a list of paths
+// which are merged like the KUBECONFIG environment variable. kubeContext selects the context of the
+// kubeconfig, an empty one means the current-context. qps and burst throttle the requests of the clientsets which
+// are created with the config, zero values keep the client-go defaults.
+func GetConfig(kubeConfigPath, kubeContext string, inCluster bool, qps float32, burst int) (*rest.Config, error) {
 	var (
 		config *rest.Config
 		err    error
@@ -26,104 +25,68 @@ func GetConfig(kubeConfigPath string, inCluster bool) (*rest.Config, error) {
 			return nil, err
 		}
 	} else {
-		if config, err = clientcmd.BuildConfigFromFlags("", kubeConfigPath); err != nil {
+		if config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(getLoadingRules(kubeConfigPath),
+			&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig(); err != nil {
 			return nil, err
 		}
 	}
 
+	config.QPS = qps
+	config.Burst = burst
+
 	return config, nil
 }
 
-// GetClientSet generates and returns k8s.Clientset using rest.Config
-func GetClientSet(config *rest.Config) (*kubernetes.Clientset, error) {
-	clientSet, err := kubernetes.NewForConfig(config)
+// GetContexts returns the names of the contexts of the kubeconfig which are selected by contexts, a comma separated
+// list of context names or all. It returns an error if a listed context does not exist in the kubeconfig.
+func GetContexts(kubeConfigPath, contexts string) ([]string, error) {
+	config, err := getLoadingRules(kubeConfigPath).Load()
 	if err != nil {
 		return nil, err
 	}
-	return clientSet, nil
-}
 
-func getTerminatingPods(clientSet kubernetes.Interface, namespace string, terminatingStateMinutes int32) ([]v1.Pod, error) {
-	var (
-		resultSlice []v1.Pod
-		pods        = new(v1.PodList)
-		namespaces  = new(v1.NamespaceList)
-		err         error
-	)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	if strings.ToLower(namespace) == "all" {
-		if namespaces, err = clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err != nil {
-			return nil, err
+	if strings.EqualFold(strings.TrimSpace(contexts), "all") {
+		names := make([]string, 0, len(config.Contexts))
+		for name := range config.Contexts {
+			names = append(names, name)
 		}
-	} else {
-		var ns *v1.Namespace
-		if ns, err = clientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
-			return nil, err
-		}
-
-		namespaces.Items = append(namespaces.Items, *ns)
-	}
 
-	for _, v := range namespaces.Items {
-		var nsPods *v1.PodList
-		if nsPods, err = clientSet.CoreV1().Pods(v.Name).List(ctx, metav1.ListOptions{}); err != nil {
-			return nil, err
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no context is found in kubeconfig %s", kubeConfigPath)
 		}
 
-		pods.Items = append(pods.Items, nsPods.Items...)
-	}
+		sort.Strings(names)
 
-	for _, pod := range pods.Items {
-		deletionTimestamp := pod.ObjectMeta.DeletionTimestamp
-		if deletionTimestamp != nil && deletionTimestamp.Add(time.Duration(terminatingStateMinutes)*time.Minute).Before(time.Now()) {
-			resultSlice = append(resultSlice, pod)
-		}
+		return names, nil
 	}
 
-	return resultSlice, nil
-}
-
-func getEvictedPods(clientSet kubernetes.Interface, namespace string) ([]v1.Pod, error) {
-	var (
-		evictedPods []v1.Pod
-		pods        = new(v1.PodList)
-		namespaces  = new(v1.NamespaceList)
-		err         error
-	)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	if strings.ToLower(namespace) == "all" {
-		if namespaces, err = clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{}); err != nil {
-			return nil, err
+	var names []string
+	for _, name := range strings.Split(contexts, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
 		}
-	} else {
-		nsFieldSelector := fields.ParseSelectorOrDie(fmt.Sprintf("metadata.name=%s", namespace))
-		if namespaces, err = clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
-			FieldSelector: nsFieldSelector.String(),
-		}); err != nil {
-			return nil, err
-		}
-	}
 
-	for _, v := range namespaces.Items {
-		var nsPods *v1.PodList
-		if nsPods, err = clientSet.CoreV1().Pods(v.Name).List(ctx, metav1.ListOptions{}); err != nil {
-			return nil, err
+		if _, ok := config.Contexts[name]; !ok {
+			return nil, fmt.Errorf("context %s is not found in kubeconfig %s", name, kubeConfigPath)
 		}
 
-		pods.Items = append(pods.Items, nsPods.Items...)
+		names = append(names, name)
 	}
 
-	for _, pod := range pods.Items {
-		if pod.Status.Reason == "Evicted" {
-			evictedPods = append(evictedPods, pod)
-		}
+	return names, nil
+}
+
+// GetClientSet generates and returns k8s.Clientset using rest.Config
+func GetClientSet(config *rest.Config) (*kubernetes.Clientset, error) {
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
 	}
+	return clientSet, nil
+}
 
-	return evictedPods, nil
+// CheckClientSet returns an error if the kube-apiserver can not be reached with the clientSet
+func CheckClientSet(clientSet kubernetes.Interface) error {
+	_, err := clientSet.Discovery().ServerVersion()
+	return err
 }
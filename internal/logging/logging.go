@@ -1,7 +1,9 @@
 package logging
 
 import (
+	"io"
 	"os"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,8 +12,33 @@ import (
 var (
 	logger *zap.Logger
 	Atomic zap.AtomicLevel
+	output = &switchableWriter{w: os.Stdout}
 )
 
+// switchableWriter is the zapcore.WriteSyncer of the shared logger whose underlying writer can be replaced
+type switchableWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *switchableWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.w.Write(p)
+}
+
+func (s *switchableWriter) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if syncer, ok := s.w.(zapcore.WriteSyncer); ok {
+		return syncer.Sync()
+	}
+
+	return nil
+}
+
 func init() {
 	Atomic = zap.NewAtomicLevel()
 	Atomic.SetLevel(zap.InfoLevel)
@@ -23,10 +50,18 @@ func init() {
 		EncodeTime:   zapcore.RFC3339TimeEncoder,
 		CallerKey:    "caller",
 		EncodeCaller: zapcore.FullCallerEncoder,
-	}), zapcore.Lock(os.Stdout), Atomic)))
+	}), output, Atomic)))
 }
 
 // GetLogger returns the shared *zap.Logger
 func GetLogger() *zap.Logger {
 	return logger
 }
+
+// SetOutput redirects the logs of the shared *zap.Logger to w, which is stdout by default
+func SetOutput(w io.Writer) {
+	output.mu.Lock()
+	defer output.mu.Unlock()
+
+	output.w = w
+}
@@ -1,8 +1,11 @@
 package logging
 
 import (
-	"github.com/stretchr/testify/assert"
+	"bytes"
+	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetLogger(t *testing.T) {
@@ -12,3 +15,12 @@ func TestGetLogger(t *testing.T) {
 	t.Log("will try logger for debugging")
 	logger.Info("this is a test log by *zap.Logger!")
 }
+
+func TestSetOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stdout)
+
+	GetLogger().Info("this is a redirected log")
+	assert.Contains(t, buf.String(), "this is a redirected log")
+}
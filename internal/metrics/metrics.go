@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kube_pod_terminator"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	// PodsFound counts the pods which are matched by a detector
+	PodsFound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pods_found_total",
+		Help:      "Number of pods which are matched by a detector.",
+	}, []string{"cluster", "namespace", "state", "detector"})
+	// PodsTerminated counts the pods which are terminated successfully
+	PodsTerminated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pods_terminated_total",
+		Help:      "Number of pods which are terminated successfully.",
+	}, []string{"cluster", "namespace", "state", "detector"})
+	// DeletionErrors counts the pods which could not be terminated, by the reason of the failure
+	DeletionErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deletion_errors_total",
+		Help:      "Number of pods which could not be terminated, by the reason of the failure.",
+	}, []string{"cluster", "namespace", "detector", "reason"})
+	// SafetyLimitHits counts the runs which hit a safety limit, by the limit
+	SafetyLimitHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "safety_limit_hits_total",
+		Help:      "Number of runs which hit a safety limit, by the limit.",
+	}, []string{"cluster", "limit"})
+	// Runs counts the detection runs by their result, which is one of success, partial-failure, failure or aborted
+	Runs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_total",
+		Help:      "Number of detection runs by their result.",
+	}, []string{"cluster", "result"})
+	// RunDuration observes the duration of the detection runs including the terminations
+	RunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of the detection runs including the terminations.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"cluster"})
+	// WorkerUp is 1 if the supervised worker of a kubeconfig is running, 0 if it is failed or stopped
+	WorkerUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_up",
+		Help:      "Whether the supervised worker of a kubeconfig is running.",
+	}, []string{"worker"})
+	// WorkerRestarts counts the restarts of the supervised workers after their failures
+	WorkerRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_restarts_total",
+		Help:      "Number of restarts of the supervised workers after their failures.",
+	}, []string{"worker"})
+	// LastSuccessfulRun is the unix timestamp of the last detection run which is completed without any failure
+	LastSuccessfulRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_run_timestamp_seconds",
+		Help:      "Unix timestamp of the last detection run which is completed without any failure.",
+	}, []string{"cluster"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		PodsFound,
+		PodsTerminated,
+		DeletionErrors,
+		SafetyLimitHits,
+		Runs,
+		RunDuration,
+		LastSuccessfulRun,
+		WorkerUp,
+		WorkerRestarts,
+	)
+}
+
+// Handler returns the http.Handler which serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
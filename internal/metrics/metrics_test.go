@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	PodsFound.WithLabelValues("https://127.0.0.1:6443", "default", "Running", "terminating").Inc()
+	LastSuccessfulRun.WithLabelValues("https://127.0.0.1:6443").SetToCurrentTime()
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `kube_pod_terminator_pods_found_total{cluster="https://127.0.0.1:6443",detector="terminating",namespace="default",state="Running"} 1`)
+	assert.Contains(t, string(body), "kube_pod_terminator_last_successful_run_timestamp_seconds")
+	assert.Contains(t, string(body), "go_goroutines")
+}
@@ -0,0 +1,140 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Policy overrides the options for the namespaces which it matches. Only the fields which are set in the policy
+// are overridden, the rest is inherited from the global options.
+type Policy struct {
+	// Namespaces is the list of namespace names or glob patterns such as ci-* which the policy applies to
+	Namespaces []string `json:"namespaces"`
+	// PodSelector is the label selector which further restricts the target pods in the namespaces, it is applied in
+	// addition to KubePodTerminatorOptions.PodSelector
+	PodSelector *string `json:"podSelector,omitempty"`
+	// PodFieldSelector is the field selector which further restricts the target pods in the namespaces, it is
+	// applied in addition to KubePodTerminatorOptions.PodFieldSelector
+	PodFieldSelector *string `json:"podFieldSelector,omitempty"`
+	// GracePeriodSeconds overrides KubePodTerminatorOptions.GracePeriodSeconds
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// TerminateEvicted overrides KubePodTerminatorOptions.TerminateEvicted
+	TerminateEvicted *bool `json:"terminateEvicted,omitempty"`
+	// EvictedStateMinutes overrides KubePodTerminatorOptions.EvictedStateMinutes
+	EvictedStateMinutes *int32 `json:"evictedStateMinutes,omitempty"`
+	// TerminatingStateMinutes overrides KubePodTerminatorOptions.TerminatingStateMinutes
+	TerminatingStateMinutes *int32 `json:"terminatingStateMinutes,omitempty"`
+	// TerminateCrashLoop overrides KubePodTerminatorOptions.TerminateCrashLoop
+	TerminateCrashLoop *bool `json:"terminateCrashLoop,omitempty"`
+	// CrashLoopStateMinutes overrides KubePodTerminatorOptions.CrashLoopStateMinutes
+	CrashLoopStateMinutes *int32 `json:"crashLoopStateMinutes,omitempty"`
+	// CrashLoopRestartCount overrides KubePodTerminatorOptions.CrashLoopRestartCount
+	CrashLoopRestartCount *int32 `json:"crashLoopRestartCount,omitempty"`
+	// TerminateImagePullErrors overrides KubePodTerminatorOptions.TerminateImagePullErrors
+	TerminateImagePullErrors *bool `json:"terminateImagePullErrors,omitempty"`
+	// ImagePullErrorStateMinutes overrides KubePodTerminatorOptions.ImagePullErrorStateMinutes
+	ImagePullErrorStateMinutes *int32 `json:"imagePullErrorStateMinutes,omitempty"`
+	// SucceededRetentionMinutes overrides KubePodTerminatorOptions.SucceededRetentionMinutes
+	SucceededRetentionMinutes *int32 `json:"succeededRetentionMinutes,omitempty"`
+	// FailedRetentionMinutes overrides KubePodTerminatorOptions.FailedRetentionMinutes
+	FailedRetentionMinutes *int32 `json:"failedRetentionMinutes,omitempty"`
+}
+
+// LoadConfig reads the YAML or JSON config file and returns a copy of base which is overridden by the file, so that
+// command line flags act as the defaults of the config file. Unknown fields are rejected.
+func LoadConfig(configFile string, base *KubePodTerminatorOptions) (*KubePodTerminatorOptions, error) {
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := *base
+	opts.Policies = nil
+	if err := yaml.UnmarshalStrict(content, &opts); err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %w", configFile, err)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &opts, nil
+}
+
+// ForNamespace returns the options which are overridden by the first policy that matches the namespace, or opts
+// itself if there is no matching policy
+func (opts *KubePodTerminatorOptions) ForNamespace(namespace string) *KubePodTerminatorOptions {
+	for _, policy := range opts.Policies {
+		if policy.matches(namespace) {
+			return policy.Apply(opts)
+		}
+	}
+
+	return opts
+}
+
+func (p Policy) matches(namespace string) bool {
+	return matchesAny(p.Namespaces, namespace)
+}
+
+// Apply returns a copy of base which is overridden by the fields that are set in the policy. Selectors of the policy
+// are joined with the selectors of base instead of overriding them.
+func (p Policy) Apply(base *KubePodTerminatorOptions) *KubePodTerminatorOptions {
+	opts := *base
+	if p.PodSelector != nil {
+		opts.PodSelector = JoinSelectors(base.PodSelector, *p.PodSelector)
+	}
+
+	if p.PodFieldSelector != nil {
+		opts.PodFieldSelector = JoinSelectors(base.PodFieldSelector, *p.PodFieldSelector)
+	}
+
+	setIfNotNil(&opts.GracePeriodSeconds, p.GracePeriodSeconds)
+	setIfNotNil(&opts.TerminateEvicted, p.TerminateEvicted)
+	setIfNotNil(&opts.EvictedStateMinutes, p.EvictedStateMinutes)
+	setIfNotNil(&opts.TerminatingStateMinutes, p.TerminatingStateMinutes)
+	setIfNotNil(&opts.TerminateCrashLoop, p.TerminateCrashLoop)
+	setIfNotNil(&opts.CrashLoopStateMinutes, p.CrashLoopStateMinutes)
+	setIfNotNil(&opts.CrashLoopRestartCount, p.CrashLoopRestartCount)
+	setIfNotNil(&opts.TerminateImagePullErrors, p.TerminateImagePullErrors)
+	setIfNotNil(&opts.ImagePullErrorStateMinutes, p.ImagePullErrorStateMinutes)
+	setIfNotNil(&opts.SucceededRetentionMinutes, p.SucceededRetentionMinutes)
+	setIfNotNil(&opts.FailedRetentionMinutes, p.FailedRetentionMinutes)
+
+	return &opts
+}
+
+func (p Policy) validate() error {
+	if len(p.Namespaces) == 0 {
+		return errors.New("namespaces can not be empty")
+	}
+
+	if err := validatePatterns(p.Namespaces); err != nil {
+		return err
+	}
+
+	var labelSelector, fieldSelector string
+	setIfNotNil(&labelSelector, p.PodSelector)
+	setIfNotNil(&fieldSelector, p.PodFieldSelector)
+
+	return validateSelectors(labelSelector, fieldSelector)
+}
+
+// JoinSelectors returns a selector which selects the objects that are selected by both of the label or field
+// selectors, since the requirements of both kinds are ANDed with commas
+func JoinSelectors(first, second string) string {
+	if first == "" || second == "" {
+		return first + second
+	}
+
+	return first + "," + second
+}
+
+func setIfNotNil[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
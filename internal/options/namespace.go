@@ -0,0 +1,67 @@
+package options
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// IncludedNamespaces returns the names or glob patterns of the target namespaces, nil means all namespaces
+func (opts *KubePodTerminatorOptions) IncludedNamespaces() []string {
+	var patterns []string
+	for _, pattern := range splitList(opts.Namespace) {
+		if strings.EqualFold(pattern, "all") {
+			return nil
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
+
+// SelectsNamespace returns true if the namespace is included and not excluded by the options. NamespaceSelector is
+// not evaluated here since it needs the labels of the namespace.
+func (opts *KubePodTerminatorOptions) SelectsNamespace(namespace string) bool {
+	if matchesAny(splitList(opts.ExcludeNamespaces), namespace) {
+		return false
+	}
+
+	included := opts.IncludedNamespaces()
+
+	return included == nil || matchesAny(included, namespace)
+}
+
+// matchesAny returns true if the namespace matches any of the names or glob patterns
+func matchesAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validatePatterns returns an error for the first glob pattern which can not be parsed
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// splitList splits the comma separated list and drops the empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,51 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectsNamespace(t *testing.T) {
+	cases := []struct {
+		caseName, namespace, excludeNamespaces, target string
+		expected                                       bool
+	}{
+		{"case1", "all", "", "default", true},
+		{"case2", "all", "kube-system", "kube-system", false},
+		{"case3", "default", "", "default", true},
+		{"case4", "default", "", "prod", false},
+		{"case5", "team-*, prod", "team-b", "team-a", true},
+		{"case6", "team-*, prod", "team-b", "team-b", false},
+		{"case7", "team-*, prod", "team-b", "prod", true},
+		{"case8", "ALL", "kube-*", "kube-public", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{Namespace: tc.namespace, ExcludeNamespaces: tc.excludeNamespaces}
+			assert.Equal(t, tc.expected, opts.SelectsNamespace(tc.target))
+		})
+	}
+}
+
+func TestValidateNamespaces(t *testing.T) {
+	cases := []struct {
+		caseName, namespace, excludeNamespaces, namespaceSelector string
+		shouldFail                                                bool
+	}{
+		{"case1", "team-*,prod", "kube-system", "terminator.io/enabled=true", false},
+		{"case2", "team-[", "", "", true},
+		{"case3", "all", "kube-[", "", true},
+		{"case4", "all", "", "terminator.io/enabled in (true", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, Namespace: tc.namespace,
+				ExcludeNamespaces: tc.excludeNamespaces, NamespaceSelector: tc.namespaceSelector}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
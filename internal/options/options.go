@@ -1,5 +1,28 @@
 package options
 
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// DryRunNone disables the dry-run, target pods are terminated
+	DryRunNone = "none"
+	// DryRunClient only reports the target pods without sending any deletion request
+	DryRunClient = "client"
+	// DryRunServer sends the deletion requests with DryRun=All, so they are validated but not persisted by the server
+	DryRunServer = "server"
+
+	// OutputTable prints the run report as a table
+	OutputTable = "table"
+	// OutputJSON prints the run report as JSON
+	OutputJSON = "json"
+	// OutputYAML prints the run report as YAML
+	OutputYAML = "yaml"
+)
+
 var kubePodTerminatorOptions = &KubePodTerminatorOptions{}
 
 // GetKubePodTerminatorOptions returns the pointer of SynFloodOptions
@@ -7,26 +30,266 @@ func GetKubePodTerminatorOptions() *KubePodTerminatorOptions {
 	return kubePodTerminatorOptions
 }
 
-// KubePodTerminatorOptions contains frequent command line and application options.
+// KubePodTerminatorOptions contains frequent command line and application options. Options which are tagged with
+// json can also be set in the config file.
 type KubePodTerminatorOptions struct {
 	// InCluster is the if kube-pod-terminator is running in cluster or not
-	InCluster bool
+	InCluster bool `json:"-"`
 	// KubeConfigPaths is the comma separated list of kubeconfig file paths to access with the cluster
-	KubeConfigPaths string
-	// Namespace is the namespace of the kube-pod-terminator run on
-	Namespace string
+	KubeConfigPaths string `json:"-"`
+	// KubeConfigDir is the directory whose files are each treated as a kubeconfig, files which are added to or
+	// removed from it are picked up in the long-running mode
+	KubeConfigDir string `json:"-"`
+	// Contexts is the comma separated list of kubeconfig contexts to run on for each kubeconfig path, all means all
+	// contexts and empty means the current-context
+	Contexts string `json:"-"`
+	// Namespace is the comma separated list of namespace names or glob patterns of the kube-pod-terminator run on,
+	// all means all namespaces
+	Namespace string `json:"namespace"`
+	// ExcludeNamespaces is the comma separated list of namespace names or glob patterns which are never touched
+	ExcludeNamespaces string `json:"excludeNamespaces"`
+	// NamespaceSelector is the label selector which restricts the target namespaces
+	NamespaceSelector string `json:"namespaceSelector"`
 	// TickerIntervalMinutes is the Interval of scheduled job to run
-	TickerIntervalMinutes int32
+	TickerIntervalMinutes int32 `json:"tickerIntervalMinutes"`
+	// PodSelector is the label selector which restricts the target pods, it is pushed into the list calls
+	PodSelector string `json:"podSelector"`
+	// PodFieldSelector is the field selector which restricts the target pods, it is pushed into the list calls
+	PodFieldSelector string `json:"podFieldSelector"`
 	// GracePeriodSeconds is the grace period to delete pods
-	GracePeriodSeconds int64
+	GracePeriodSeconds int64 `json:"gracePeriodSeconds"`
+	// UseEviction is a boolean flag to tell if live pods are evicted through the Eviction API instead of being deleted,
+	// so that PodDisruptionBudgets are respected
+	UseEviction bool `json:"useEviction"`
+	// ForceDelete is a boolean flag to tell if pods which still exist after the normal deletion are deleted with zero
+	// grace period
+	ForceDelete bool `json:"forceDelete"`
+	// RemoveFinalizers is a boolean flag to tell if finalizers of the pods which still exist after the forced deletion
+	// are removed
+	RemoveFinalizers bool `json:"removeFinalizers"`
+	// EscalationWaitSeconds is the duration to wait for a pod to be deleted before escalating to the next step
+	EscalationWaitSeconds int32 `json:"escalationWaitSeconds"`
 	// TerminateEvicted is a boolean flag to tell if terminating evicted pods is supported
-	TerminateEvicted bool
+	TerminateEvicted bool `json:"terminateEvicted"`
+	// EvictedStateMinutes is the specifier to select pods which are more in evicted state
+	EvictedStateMinutes int32 `json:"evictedStateMinutes"`
 	// TerminatingStateMinutes is the specifier to select pods which are more in terminating state
-	TerminatingStateMinutes int32
+	TerminatingStateMinutes int32 `json:"terminatingStateMinutes"`
+	// TerminateCrashLoop is a boolean flag to tell if terminating pods in CrashLoopBackOff is supported
+	TerminateCrashLoop bool `json:"terminateCrashLoop"`
+	// CrashLoopStateMinutes is the specifier to select pods which are more in CrashLoopBackOff state
+	CrashLoopStateMinutes int32 `json:"crashLoopStateMinutes"`
+	// CrashLoopRestartCount is the specifier to select pods in CrashLoopBackOff state which restarted more, 0 disables it
+	CrashLoopRestartCount int32 `json:"crashLoopRestartCount"`
+	// TerminateImagePullErrors is a boolean flag to tell if terminating pods which can not pull their images is supported
+	TerminateImagePullErrors bool `json:"terminateImagePullErrors"`
+	// ImagePullErrorStateMinutes is the specifier to select pods which are more in ImagePullBackOff, ErrImagePull
+	// or InvalidImageName state
+	ImagePullErrorStateMinutes int32 `json:"imagePullErrorStateMinutes"`
+	// SucceededRetentionMinutes is the specifier to select pods which are more in Succeeded phase, 0 disables it
+	SucceededRetentionMinutes int32 `json:"succeededRetentionMinutes"`
+	// FailedRetentionMinutes is the specifier to select pods which are more in Failed phase, 0 disables it
+	FailedRetentionMinutes int32 `json:"failedRetentionMinutes"`
+	// MaxDeletionsPerRun is the maximum number of the pods which are terminated in a run, or between two ticks in the
+	// long-running mode, the rest is left to the next runs, 0 disables it
+	MaxDeletionsPerRun int32 `json:"maxDeletionsPerRun"`
+	// MaxNamespaceDeletionPercentage is the maximum percentage of the pods in a namespace which are terminated in a
+	// run, the rest is left to the next runs, 0 disables it
+	MaxNamespaceDeletionPercentage int32 `json:"maxNamespaceDeletionPercentage"`
+	// AbortCandidateThreshold is the number of the matched pods above which the run is aborted without terminating
+	// any pod, 0 disables it
+	AbortCandidateThreshold int32 `json:"abortCandidateThreshold"`
+	// Workers is the number of the workers which terminate the matched pods concurrently, 0 means a single worker
+	Workers int `json:"workers"`
+	// DeleteQPS is the maximum number of the deletion requests per second of a cluster across all the workers and
+	// the runs, 0 disables it
+	DeleteQPS float32 `json:"deleteQPS"`
+	// DeleteBurst is the number of the deletion requests which can be sent at once before DeleteQPS is enforced
+	DeleteBurst int `json:"deleteBurst"`
+	// KubeAPIQPS is the maximum number of the requests per second of the clientset to the kube-apiserver
+	KubeAPIQPS float32 `json:"-"`
+	// KubeAPIBurst is the number of the requests of the clientset which can be sent at once before KubeAPIQPS is
+	// enforced
+	KubeAPIBurst int `json:"-"`
+	// ListTimeoutSeconds is the timeout of the list calls of a run and of the initial sync of the informer cache, 0
+	// disables it for the runs
+	ListTimeoutSeconds int32 `json:"listTimeoutSeconds"`
+	// DrainTimeoutSeconds is the duration that the in-flight deletions are given to finish on shutdown before they
+	// are cancelled
+	DrainTimeoutSeconds int32 `json:"-"`
+	// RestartBackoffInitialSeconds is the duration to wait before restarting the failed worker of a kubeconfig,
+	// it is doubled on each consecutive failure
+	RestartBackoffInitialSeconds int32 `json:"-"`
+	// RestartBackoffMaxSeconds is the maximum duration to wait before restarting the failed worker of a kubeconfig
+	RestartBackoffMaxSeconds int32 `json:"-"`
 	// OneShot is the specifier to run kube-pod-terminator only one time instead of continuously running in the background
-	OneShot bool
+	OneShot bool `json:"-"`
 	// BannerFilePath is the relative path to the banner file
-	BannerFilePath string
+	BannerFilePath string `json:"-"`
+	// MetricsPort is the port of the /metrics endpoint which is served in the long-running mode
+	MetricsPort int `json:"-"`
+	// HealthPort is the port of the /healthz and /readyz endpoints which are served in the long-running mode
+	HealthPort int `json:"-"`
+	// HealthStalenessMinutes is the duration on top of the ticker interval after which a worker without a completed
+	// tick is treated as wedged
+	HealthStalenessMinutes int32 `json:"-"`
+	// LeaderElect is a boolean flag to tell if the replicas elect a leader with a Lease in each cluster, so that only
+	// the leader terminates the pods
+	LeaderElect bool `json:"-"`
+	// LeaderElectionLeaseName is the name of the Lease which is used for the leader election
+	LeaderElectionLeaseName string `json:"-"`
+	// LeaderElectionNamespace is the namespace of the Lease which is used for the leader election
+	LeaderElectionNamespace string `json:"-"`
+	// LeaderElectionLeaseDurationSeconds is the duration that the standby replicas wait before taking over the Lease
+	LeaderElectionLeaseDurationSeconds int32 `json:"-"`
+	// LeaderElectionRenewDeadlineSeconds is the duration that the leader retries renewing the Lease before giving up
+	LeaderElectionRenewDeadlineSeconds int32 `json:"-"`
+	// LeaderElectionRetryPeriodSeconds is the duration that the replicas wait between the tries of the Lease actions
+	LeaderElectionRetryPeriodSeconds int32 `json:"-"`
 	// VerboseLog is the verbosity of the logging library
-	VerboseLog bool
+	VerboseLog bool `json:"verbose"`
+	// DryRun is the dry-run mode, must be one of none, client or server
+	DryRun string `json:"dryRun"`
+	// Output is the format of the run report which is printed to stdout in the one-shot mode, must be one of table,
+	// json or yaml, empty disables the report
+	Output string `json:"-"`
+	// DetailedExitCodes makes the one-shot mode exit with a distinct code for each outcome of the runs instead of 0
+	DetailedExitCodes bool `json:"-"`
+	// ConfigFile is the path of the YAML or JSON file which overrides these options and contains the per-namespace policies
+	ConfigFile string `json:"-"`
+	// Policies overrides the options for the namespaces which they match, the first matching policy wins
+	Policies []Policy `json:"policies"`
+}
+
+// IsDryRun returns true if the target pods should only be reported instead of being terminated
+func (opts *KubePodTerminatorOptions) IsDryRun() bool {
+	return opts.DryRun == DryRunClient || opts.DryRun == DryRunServer
+}
+
+// Validate checks the options and returns an error for the first invalid one
+func (opts *KubePodTerminatorOptions) Validate() error {
+	if err := opts.validateModes(); err != nil {
+		return err
+	}
+
+	if opts.InCluster && (opts.Contexts != "" || opts.KubeConfigDir != "") {
+		return fmt.Errorf("contexts %q and kubeconfig dir %q can not be used with in-cluster", opts.Contexts,
+			opts.KubeConfigDir)
+	}
+
+	if err := opts.validateSafetyLimits(); err != nil {
+		return err
+	}
+
+	if err := opts.validateRateLimits(); err != nil {
+		return err
+	}
+
+	if err := opts.validateTimeouts(); err != nil {
+		return err
+	}
+
+	if err := validateSelectors(opts.PodSelector, opts.PodFieldSelector); err != nil {
+		return err
+	}
+
+	if err := validatePatterns(append(splitList(opts.Namespace), splitList(opts.ExcludeNamespaces)...)); err != nil {
+		return err
+	}
+
+	if _, err := labels.Parse(opts.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace selector %q: %w", opts.NamespaceSelector, err)
+	}
+
+	for i, policy := range opts.Policies {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("invalid policy at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateModes returns an error if the dry-run mode or the output format is unknown
+func (opts *KubePodTerminatorOptions) validateModes() error {
+	switch opts.DryRun {
+	case DryRunNone, DryRunClient, DryRunServer:
+	default:
+		return fmt.Errorf("invalid dry-run mode %q, must be one of %s, %s or %s", opts.DryRun, DryRunNone,
+			DryRunClient, DryRunServer)
+	}
+
+	switch opts.Output {
+	case "":
+	case OutputTable, OutputJSON, OutputYAML:
+		if !opts.OneShot {
+			return fmt.Errorf("output %q can only be used with one-shot", opts.Output)
+		}
+	default:
+		return fmt.Errorf("invalid output %q, must be one of %s, %s or %s", opts.Output, OutputTable, OutputJSON,
+			OutputYAML)
+	}
+
+	return nil
+}
+
+// validateSelectors returns an error if the label selector or the field selector can not be parsed
+func validateSelectors(labelSelector, fieldSelector string) error {
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return fmt.Errorf("invalid pod selector %q: %w", labelSelector, err)
+	}
+
+	if _, err := fields.ParseSelector(fieldSelector); err != nil {
+		return fmt.Errorf("invalid pod field selector %q: %w", fieldSelector, err)
+	}
+
+	return nil
+}
+
+// validateSafetyLimits returns an error if any of the safety limits is out of its range
+func (opts *KubePodTerminatorOptions) validateSafetyLimits() error {
+	if opts.MaxDeletionsPerRun < 0 || opts.AbortCandidateThreshold < 0 {
+		return fmt.Errorf("max deletions per run %d and abort candidate threshold %d can not be negative",
+			opts.MaxDeletionsPerRun, opts.AbortCandidateThreshold)
+	}
+
+	if opts.MaxNamespaceDeletionPercentage < 0 || opts.MaxNamespaceDeletionPercentage > 100 {
+		return fmt.Errorf("invalid max namespace deletion percentage %d, must be between 0 and 100",
+			opts.MaxNamespaceDeletionPercentage)
+	}
+
+	return nil
+}
+
+// validateRateLimits returns an error if the worker count or any of the rate limits is negative
+func (opts *KubePodTerminatorOptions) validateRateLimits() error {
+	if opts.Workers < 0 {
+		return fmt.Errorf("invalid worker count %d, can not be negative", opts.Workers)
+	}
+
+	if opts.DeleteQPS < 0 || opts.DeleteBurst < 0 {
+		return fmt.Errorf("delete qps %v and delete burst %d can not be negative", opts.DeleteQPS, opts.DeleteBurst)
+	}
+
+	if opts.KubeAPIQPS < 0 || opts.KubeAPIBurst < 0 {
+		return fmt.Errorf("kube api qps %v and kube api burst %d can not be negative", opts.KubeAPIQPS,
+			opts.KubeAPIBurst)
+	}
+
+	return nil
+}
+
+// validateTimeouts returns an error if any of the timeouts is negative
+func (opts *KubePodTerminatorOptions) validateTimeouts() error {
+	if opts.ListTimeoutSeconds < 0 || opts.DrainTimeoutSeconds < 0 {
+		return fmt.Errorf("list timeout %d and drain timeout %d can not be negative", opts.ListTimeoutSeconds,
+			opts.DrainTimeoutSeconds)
+	}
+
+	if opts.RestartBackoffInitialSeconds < 0 || opts.RestartBackoffMaxSeconds < opts.RestartBackoffInitialSeconds {
+		return fmt.Errorf("invalid restart backoff, initial %d can not be negative or more than max %d",
+			opts.RestartBackoffInitialSeconds, opts.RestartBackoffMaxSeconds)
+	}
+
+	return nil
 }
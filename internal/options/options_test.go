@@ -11,3 +11,237 @@ func TestGetKubePodTerminatorOptions(t *testing.T) {
 	assert.NotNil(t, opts)
 	t.Logf("fetched default options.KubePodTerminatorOptions, %v\n", opts)
 }
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		caseName, dryRun string
+		shouldFail       bool
+	}{
+		{"case1", DryRunNone, false},
+		{"case2", DryRunClient, false},
+		{"case3", DryRunServer, false},
+		{"case4", "all", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: tc.dryRun}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	base := &KubePodTerminatorOptions{
+		DryRun:                  DryRunNone,
+		Namespace:               "all",
+		TerminateEvicted:        false,
+		TerminatingStateMinutes: 30,
+	}
+
+	opts, err := LoadConfig("../../test/config.yaml", base)
+	assert.Nil(t, err)
+	assert.NotNil(t, opts)
+	assert.Equal(t, "all", opts.Namespace)
+	assert.Equal(t, int32(45), opts.TerminatingStateMinutes)
+	assert.Equal(t, int32(30), base.TerminatingStateMinutes)
+	assert.Len(t, opts.Policies, 2)
+
+	prod := opts.ForNamespace("prod")
+	assert.Equal(t, int32(10), prod.TerminatingStateMinutes)
+	assert.True(t, prod.TerminateEvicted)
+	assert.Equal(t, int32(60), prod.EvictedStateMinutes)
+
+	ci := opts.ForNamespace("ci-1234")
+	assert.Equal(t, int32(45), ci.TerminatingStateMinutes)
+	assert.Equal(t, int32(30), ci.SucceededRetentionMinutes)
+
+	assert.Equal(t, opts, opts.ForNamespace("default"))
+
+	_, err = LoadConfig("../../test/broken_config.yaml", base)
+	assert.NotNil(t, err)
+
+	_, err = LoadConfig("../../test/nonexisting_config.yaml", base)
+	assert.NotNil(t, err)
+}
+
+func TestValidatePolicies(t *testing.T) {
+	cases := []struct {
+		caseName   string
+		policy     Policy
+		shouldFail bool
+	}{
+		{"case1", Policy{Namespaces: []string{"prod", "ci-*"}}, false},
+		{"case2", Policy{}, true},
+		{"case3", Policy{Namespaces: []string{"ci-["}}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, Policies: []Policy{tc.policy}}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestValidateSelectors(t *testing.T) {
+	invalidSelector := "team in (batch"
+	cases := []struct {
+		caseName, podSelector, podFieldSelector string
+		policySelector                          *string
+		shouldFail                              bool
+	}{
+		{"case1", "team=batch,tier!=frontend", "spec.nodeName=node-1", nil, false},
+		{"case2", "team in (batch", "", nil, true},
+		{"case3", "", "spec.nodeName", nil, true},
+		{"case4", "", "", &invalidSelector, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, PodSelector: tc.podSelector,
+				PodFieldSelector: tc.podFieldSelector}
+			if tc.policySelector != nil {
+				opts.Policies = []Policy{{Namespaces: []string{"prod"}, PodSelector: tc.policySelector}}
+			}
+
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestApplySelectors(t *testing.T) {
+	podSelector := "tier=worker"
+	podFieldSelector := "spec.nodeName=node-1"
+	base := &KubePodTerminatorOptions{PodSelector: "team=batch"}
+
+	opts := Policy{PodSelector: &podSelector, PodFieldSelector: &podFieldSelector}.Apply(base)
+	assert.Equal(t, "team=batch,tier=worker", opts.PodSelector)
+	assert.Equal(t, "spec.nodeName=node-1", opts.PodFieldSelector)
+	assert.Equal(t, "team=batch", Policy{}.Apply(base).PodSelector)
+}
+
+func TestValidateSafetyLimits(t *testing.T) {
+	cases := []struct {
+		caseName                                          string
+		maxDeletionsPerRun, maxNamespacePercentage, abort int32
+		shouldFail                                        bool
+	}{
+		{"case1", 50, 20, 500, false},
+		{"case2", -1, 0, 0, true},
+		{"case3", 0, 101, 0, true},
+		{"case4", 0, 0, -1, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, MaxDeletionsPerRun: tc.maxDeletionsPerRun,
+				MaxNamespaceDeletionPercentage: tc.maxNamespacePercentage, AbortCandidateThreshold: tc.abort}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	cases := []struct {
+		caseName                        string
+		workers, deleteBurst, kubeBurst int
+		deleteQPS, kubeQPS              float32
+		shouldFail                      bool
+	}{
+		{"case1", 5, 10, 40, 5, 20, false},
+		{"case2", 0, 0, 0, 0, 0, false},
+		{"case3", -1, 0, 0, 0, 0, true},
+		{"case4", 0, -1, 0, 0, 0, true},
+		{"case5", 0, 0, 0, -0.5, 0, true},
+		{"case6", 0, 0, -1, 0, 0, true},
+		{"case7", 0, 0, 0, 0, -1, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, Workers: tc.workers, DeleteQPS: tc.deleteQPS,
+				DeleteBurst: tc.deleteBurst, KubeAPIQPS: tc.kubeQPS, KubeAPIBurst: tc.kubeBurst}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	cases := []struct {
+		caseName                                 string
+		listTimeout, drainTimeout                int32
+		restartBackoffInitial, restartBackoffMax int32
+		shouldFail                               bool
+	}{
+		{"case1", 60, 30, 5, 300, false},
+		{"case2", 0, 0, 0, 0, false},
+		{"case3", -1, 30, 0, 0, true},
+		{"case4", 60, -1, 0, 0, true},
+		{"case5", 60, 30, -1, 300, true},
+		{"case6", 60, 30, 300, 5, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, ListTimeoutSeconds: tc.listTimeout,
+				DrainTimeoutSeconds: tc.drainTimeout, RestartBackoffInitialSeconds: tc.restartBackoffInitial,
+				RestartBackoffMaxSeconds: tc.restartBackoffMax}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestValidateContexts(t *testing.T) {
+	cases := []struct {
+		caseName                string
+		inCluster               bool
+		contexts, kubeConfigDir string
+		shouldFail              bool
+	}{
+		{"case1", false, "all", "", false},
+		{"case2", false, "staging,production", "/etc/kubeconfigs", false},
+		{"case3", true, "", "", false},
+		{"case4", true, "all", "", true},
+		{"case5", true, "", "/etc/kubeconfigs", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, InCluster: tc.inCluster, Contexts: tc.contexts,
+				KubeConfigDir: tc.kubeConfigDir}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	cases := []struct {
+		caseName   string
+		output     string
+		oneShot    bool
+		shouldFail bool
+	}{
+		{"case1", "", false, false},
+		{"case2", OutputTable, true, false},
+		{"case3", OutputJSON, true, false},
+		{"case4", OutputYAML, true, false},
+		{"case5", OutputJSON, false, true},
+		{"case6", "xml", true, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			opts := &KubePodTerminatorOptions{DryRun: DryRunNone, Output: tc.output, OneShot: tc.oneShot}
+			err := opts.Validate()
+			assert.Equal(t, tc.shouldFail, err != nil)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Reloader keeps the current options which are loaded from the config file on top of the command line flags and
+// swaps them atomically when the config file is reloaded, so that readers always see a consistent snapshot
+type Reloader struct {
+	configFile string
+	base       *KubePodTerminatorOptions
+	current    atomic.Pointer[KubePodTerminatorOptions]
+}
+
+// NewReloader returns a Reloader which serves initial until the config file is reloaded. base is the options which
+// are populated from the command line flags, the config file is loaded on top of it on each reload.
+func NewReloader(configFile string, base, initial *KubePodTerminatorOptions) *Reloader {
+	r := &Reloader{configFile: configFile, base: base}
+	r.current.Store(initial)
+
+	return r
+}
+
+// Get returns the current options, callers should get them once and use the same snapshot during a run
+func (r *Reloader) Get() *KubePodTerminatorOptions {
+	return r.current.Load()
+}
+
+// Reload loads and validates the config file again and swaps the current options with the new ones. If the new
+// options are rejected, the current ones are kept active and the error is returned. If the config file is not
+// changed, the current options are returned as is.
+func (r *Reloader) Reload() (*KubePodTerminatorOptions, error) {
+	if r.configFile == "" {
+		return nil, errors.New("no config file is specified to reload")
+	}
+
+	opts, err := LoadConfig(r.configFile, r.base)
+	if err != nil {
+		return nil, err
+	}
+
+	current := r.Get()
+	if err := checkRestartRequired(current, opts); err != nil {
+		return nil, err
+	}
+
+	if reflect.DeepEqual(opts, current) {
+		return current, nil
+	}
+
+	r.current.Store(opts)
+
+	return opts, nil
+}
+
+// checkRestartRequired returns an error if the new options change a field which the pod informers are started with
+func checkRestartRequired(current, opts *KubePodTerminatorOptions) error {
+	for _, field := range []struct{ name, current, new string }{
+		{"namespace", current.Namespace, opts.Namespace},
+		{"podSelector", current.PodSelector, opts.PodSelector},
+		{"podFieldSelector", current.PodFieldSelector, opts.PodFieldSelector},
+	} {
+		if field.current != field.new {
+			return fmt.Errorf("%s can not be changed without a restart, current %q, new %q", field.name,
+				field.current, field.new)
+		}
+	}
+
+	return nil
+}
+
+// Watch reloads the config file when it is changed or SIGHUP is received, until stopCh is closed. The parent
+// directory is watched instead of the file itself, since ConfigMap volumes update the files by swapping the ..data
+// symlink. onReload is called with the result of each reload which changes the options or fails.
+func (r *Reloader) Watch(stopCh <-chan struct{}, onReload func(opts *KubePodTerminatorOptions, err error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(filepath.Dir(r.configFile)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sighup)
+		defer func() {
+			_ = watcher.Close()
+		}()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-sighup:
+				r.reload(onReload)
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if r.isConfigEvent(event) {
+					r.reload(onReload)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				onReload(nil, err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (r *Reloader) reload(onReload func(opts *KubePodTerminatorOptions, err error)) {
+	previous := r.Get()
+	opts, err := r.Reload()
+	if err != nil || opts != previous {
+		onReload(opts, err)
+	}
+}
+
+// isConfigEvent returns true if the event is about the config file itself or the ..data symlink of a ConfigMap volume
+func (r *Reloader) isConfigEvent(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	return filepath.Clean(event.Name) == filepath.Clean(r.configFile) || filepath.Base(event.Name) == "..data"
+}
@@ -0,0 +1,90 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, configFile, content string) {
+	assert.Nil(t, os.WriteFile(configFile, []byte(content), 0o600))
+}
+
+func TestReload(t *testing.T) {
+	base := &KubePodTerminatorOptions{DryRun: DryRunNone, Namespace: "all", TerminatingStateMinutes: 30}
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, configFile, "terminatingStateMinutes: 45\n")
+
+	initial, err := LoadConfig(configFile, base)
+	assert.Nil(t, err)
+
+	reloader := NewReloader(configFile, base, initial)
+	assert.Equal(t, initial, reloader.Get())
+
+	opts, err := reloader.Reload()
+	assert.Nil(t, err)
+	assert.Same(t, initial, opts)
+
+	writeConfig(t, configFile, "terminatingStateMinutes: 15\n")
+	opts, err = reloader.Reload()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(15), opts.TerminatingStateMinutes)
+	assert.Same(t, opts, reloader.Get())
+
+	writeConfig(t, configFile, "terminatingStateMinute: 5\n")
+	_, err = reloader.Reload()
+	assert.NotNil(t, err)
+	assert.Same(t, opts, reloader.Get())
+
+	writeConfig(t, configFile, "namespace: default\n")
+	_, err = reloader.Reload()
+	assert.NotNil(t, err)
+	assert.Same(t, opts, reloader.Get())
+
+	writeConfig(t, configFile, "podSelector: team=batch\n")
+	_, err = reloader.Reload()
+	assert.NotNil(t, err)
+	assert.Same(t, opts, reloader.Get())
+
+	_, err = NewReloader("", base, base).Reload()
+	assert.NotNil(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	base := &KubePodTerminatorOptions{DryRun: DryRunNone, Namespace: "all", TerminatingStateMinutes: 30}
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, configFile, "terminatingStateMinutes: 45\n")
+
+	initial, err := LoadConfig(configFile, base)
+	assert.Nil(t, err)
+
+	reloaded := make(chan *KubePodTerminatorOptions, 10)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	reloader := NewReloader(configFile, base, initial)
+	assert.Nil(t, reloader.Watch(stopCh, func(opts *KubePodTerminatorOptions, err error) {
+		if err == nil {
+			reloaded <- opts
+		}
+	}))
+
+	writeConfig(t, configFile, "terminatingStateMinutes: 15\n")
+
+	// a write can be observed in several events, wait until the final content is loaded
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case opts := <-reloaded:
+			if opts.TerminatingStateMinutes == 15 {
+				assert.Same(t, opts, reloader.Get())
+				return
+			}
+		case <-timeout:
+			t.Fatal("config file is not reloaded")
+		}
+	}
+}
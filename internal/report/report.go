@@ -0,0 +1,133 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/k8s"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ExitNothingFound means that no candidate pod is found in any cluster
+	ExitNothingFound = 0
+	// ExitTerminated means that candidate pods are found, and they are terminated, would be terminated in dry-run
+	// or are left to the next runs, without any failure
+	ExitTerminated = 2
+	// ExitPartialFailure means that some pods could not be terminated, or a run is aborted by the safety limits or
+	// interrupted by the shutdown
+	ExitPartialFailure = 3
+	// ExitUnreachable means that some clusters could not be reached
+	ExitUnreachable = 4
+)
+
+// Collector collects the reports of the clusters which are run concurrently
+type Collector struct {
+	mu       sync.Mutex
+	reports  []*k8s.Report
+	exitCode int
+}
+
+// NewCollector returns an empty Collector
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Add adds the report of a cluster with the error of its run
+func (c *Collector) Add(report *k8s.Report, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reports = append(c.reports, report)
+	c.exitCode = max(c.exitCode, GetExitCode(report, err))
+}
+
+// ExitCode returns the most severe exit code of the collected reports, the exit codes are in the order of severity.
+// It is always 0 unless detailed is true, so that a successful cleanup does not fail the Job which runs it.
+func (c *Collector) ExitCode(detailed bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !detailed {
+		return ExitNothingFound
+	}
+
+	return c.exitCode
+}
+
+// GetExitCode returns the exit code of a run with the report and the error which are returned by k8s.Run
+func GetExitCode(report *k8s.Report, err error) int {
+	switch {
+	case errors.Is(err, k8s.ErrClusterUnreachable):
+		return ExitUnreachable
+	case err != nil:
+		return ExitPartialFailure
+	case len(report.Pods) > 0:
+		return ExitTerminated
+	}
+
+	return ExitNothingFound
+}
+
+// Reports returns the collected reports in the order of their clusters
+func (c *Collector) Reports() []*k8s.Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reports := append([]*k8s.Report{}, c.reports...)
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].Cluster < reports[j].Cluster
+	})
+
+	return reports
+}
+
+// Write writes the reports to w in the format, which must be one of table, json or yaml
+func Write(w io.Writer, format string, reports []*k8s.Report) error {
+	switch format {
+	case options.OutputTable:
+		return writeTable(w, reports)
+	case options.OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case options.OutputYAML:
+		out, err := yaml.Marshal(reports)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(out)
+		return err
+	}
+
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// writeTable writes a row for each pod of the reports. A cluster whose run is failed before finding any pod is
+// written as a single row with its error, so that it is not mistaken for a cluster without any candidate.
+func writeTable(w io.Writer, reports []*k8s.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CLUSTER\tNAMESPACE\tNAME\tDETECTOR\tAGE\tACTION\tRESULT\tERROR")
+	for _, report := range reports {
+		if len(report.Pods) == 0 && report.Result != k8s.RunSuccess {
+			_, _ = fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t%s\t%s\n", report.Cluster, report.Result, report.Error)
+		}
+
+		for _, pod := range report.Pods {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", report.Cluster, pod.Namespace, pod.Name,
+				pod.Detector, duration.HumanDuration(time.Duration(pod.AgeInStateSeconds)*time.Second), pod.Action,
+				pod.Result, pod.Error)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/k8s"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/options"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func getReports() []*k8s.Report {
+	collector := NewCollector()
+	collector.Add(&k8s.Report{Cluster: "staging", Result: k8s.RunFailure, Error: "connection refused",
+		Pods: []k8s.PodReport{}}, nil)
+	collector.Add(&k8s.Report{Cluster: "production", Result: k8s.RunSuccess, Pods: []k8s.PodReport{
+		{Namespace: "default", Name: "varnish-pod-1", Detector: "evicted", AgeInStateSeconds: 7200,
+			Action: "delete", Result: k8s.PodTerminated},
+		{Namespace: "default", Name: "varnish-pod-2", Detector: "crashloop", AgeInStateSeconds: 90,
+			Action: "evict", Result: k8s.PodDeferred, Error: "eviction is rejected"},
+	}}, nil)
+
+	return collector.Reports()
+}
+
+func TestCollector(t *testing.T) {
+	reports := getReports()
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "production", reports[0].Cluster)
+	assert.Equal(t, "staging", reports[1].Cluster)
+}
+
+func TestGetExitCode(t *testing.T) {
+	found := &k8s.Report{Pods: []k8s.PodReport{{Name: "varnish-pod-1", Result: k8s.PodTerminated}}}
+	cases := []struct {
+		caseName string
+		report   *k8s.Report
+		err      error
+		expected int
+	}{
+		{"case1", &k8s.Report{Pods: []k8s.PodReport{}}, nil, ExitNothingFound},
+		{"case2", found, nil, ExitTerminated},
+		{"case3", found, fmt.Errorf("%w: 1 of 1 pods are failed", k8s.ErrPartialFailure), ExitPartialFailure},
+		{"case4", found, k8s.ErrRunAborted, ExitPartialFailure},
+		{"case5", &k8s.Report{}, fmt.Errorf("%w: connection refused", k8s.ErrClusterUnreachable), ExitUnreachable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.caseName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, GetExitCode(tc.report, tc.err))
+		})
+	}
+
+	// the most severe exit code of the clusters wins
+	collector := NewCollector()
+	assert.Equal(t, ExitNothingFound, collector.ExitCode(true))
+	collector.Add(found, k8s.ErrShuttingDown)
+	collector.Add(found, nil)
+	assert.Equal(t, ExitPartialFailure, collector.ExitCode(true))
+
+	// the exit code is always 0 unless the detailed exit codes are enabled
+	assert.Equal(t, ExitNothingFound, collector.ExitCode(false))
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, options.OutputTable, getReports()))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, []string{"CLUSTER", "NAMESPACE", "NAME", "DETECTOR", "AGE", "ACTION", "RESULT", "ERROR"},
+		strings.Fields(lines[0]))
+	assert.Equal(t, []string{"production", "default", "varnish-pod-1", "evicted", "120m", "delete", "terminated"},
+		strings.Fields(lines[1]))
+	assert.Equal(t, []string{"staging", "-", "-", "-", "-", "-", "failure", "connection", "refused"},
+		strings.Fields(lines[3]))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, options.OutputJSON, getReports()))
+
+	var reports []*k8s.Report
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &reports))
+	assert.Equal(t, getReports(), reports)
+	assert.Contains(t, buf.String(), `"ageInStateSeconds": 7200`)
+}
+
+func TestWriteYAML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, options.OutputYAML, getReports()))
+
+	var reports []*k8s.Report
+	assert.Nil(t, yaml.Unmarshal(buf.Bytes(), &reports))
+	assert.Equal(t, getReports(), reports)
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	assert.NotNil(t, Write(&bytes.Buffer{}, "xml", getReports()))
+}
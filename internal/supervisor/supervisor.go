@@ -0,0 +1,250 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	// StateRunning means the worker is running
+	StateRunning = "running"
+	// StateBackoff means the worker is failed and waiting to be restarted
+	StateBackoff = "backoff"
+	// StateFailed means the worker is failed and is not restarted since restarts are disabled
+	StateFailed = "failed"
+	// StateStopped means the worker is completed or stopped by the cancellation of its context
+	StateStopped = "stopped"
+)
+
+// Worker runs the job of a cluster with its own logger until ctx is done. A returned error means that the worker is
+// failed and should be restarted.
+type Worker func(ctx context.Context, logger *zap.Logger) error
+
+// Status is the current status of a supervised worker
+type Status struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"lastError,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// Supervisor runs each worker in isolation, so that a failing or panicking worker does not affect the others, and
+// restarts the failed ones with an exponential backoff
+type Supervisor struct {
+	mu             sync.RWMutex
+	wg             sync.WaitGroup
+	logger         *zap.Logger
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	restart        bool
+	statuses       map[string]*Status
+	entries        map[string]*entry
+	waiting        bool
+}
+
+// entry is a started worker, it is replaced when a stopped worker is started again with the same name
+type entry struct {
+	cancel context.CancelFunc
+}
+
+// New returns a Supervisor which waits initialBackoff before the first restart of a failed worker and doubles it up
+// to maxBackoff on each consecutive failure. If restart is false, failed workers are not restarted.
+func New(logger *zap.Logger, initialBackoff, maxBackoff time.Duration, restart bool) *Supervisor {
+	return &Supervisor{
+		logger:         logger,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		restart:        restart,
+		statuses:       make(map[string]*Status),
+		entries:        make(map[string]*entry),
+	}
+}
+
+// Start runs the worker with the name in a new goroutine until ctx is done or it is stopped with Stop. It returns
+// false without doing anything if a worker with the same name is already started or Wait is already called.
+func (s *Supervisor) Start(ctx context.Context, name string, worker Worker) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[name]; ok || s.waiting {
+		return false
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	e := &entry{cancel: cancel}
+	s.entries[name] = e
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.supervise(workerCtx, name, e, worker)
+	}()
+
+	return true
+}
+
+// Stop stops the worker with the name and forgets its status, it returns false if there is no such worker
+func (s *Supervisor) Stop(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return false
+	}
+
+	e.cancel()
+	delete(s.entries, name)
+	delete(s.statuses, name)
+	metrics.WorkerUp.DeleteLabelValues(name)
+
+	return true
+}
+
+// Names returns the names of the started workers in order
+func (s *Supervisor) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Wait blocks until all the workers are stopped or failed without a restart, no worker can be started afterwards
+func (s *Supervisor) Wait() {
+	s.mu.Lock()
+	s.waiting = true
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// supervise runs the worker and restarts it with backoff after each failure. The backoff is reset when the worker
+// runs longer than maxBackoff before failing.
+func (s *Supervisor) supervise(ctx context.Context, name string, e *entry, worker Worker) {
+	logger := s.logger.With(zap.String("worker", name))
+	backoff := s.initialBackoff
+	for restarts := 0; ; restarts++ {
+		s.setStatus(name, e, StateRunning, restarts, nil)
+		started := time.Now()
+		err := run(ctx, worker, logger)
+		if err == nil || ctx.Err() != nil {
+			s.setStatus(name, e, StateStopped, restarts, err)
+			return
+		}
+
+		if !s.restart {
+			logger.Error("worker is failed", zap.Error(err))
+			s.setStatus(name, e, StateFailed, restarts, err)
+			return
+		}
+
+		if time.Since(started) > s.maxBackoff {
+			backoff = s.initialBackoff
+		}
+
+		logger.Error("worker is failed, restarting after backoff", zap.Error(err), zap.Duration("backoff", backoff),
+			zap.Int("restarts", restarts))
+		s.setStatus(name, e, StateBackoff, restarts, err)
+		select {
+		case <-ctx.Done():
+			s.setStatus(name, e, StateStopped, restarts, err)
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, s.maxBackoff)
+		metrics.WorkerRestarts.WithLabelValues(name).Inc()
+	}
+}
+
+// run runs the worker with a context which is cancelled when it returns, so that everything the worker started is
+// stopped before a restart. A panic of the worker is returned as an error.
+func run(ctx context.Context, worker Worker, logger *zap.Logger) (err error) {
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("worker panicked: %v", r)
+		}
+	}()
+
+	return worker(workerCtx, logger)
+}
+
+// setStatus updates the status of the worker of the entry, unless it is stopped with Stop and so the entry is no
+// longer the current one of the name
+func (s *Supervisor) setStatus(name string, e *entry, state string, restarts int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries[name] != e {
+		return
+	}
+
+	status := &Status{Name: name, State: state, Restarts: restarts, Since: time.Now()}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+
+	s.statuses[name] = status
+	up := 0.0
+	if state == StateRunning {
+		up = 1
+	}
+
+	metrics.WorkerUp.WithLabelValues(name).Set(up)
+}
+
+// Statuses returns the current statuses of the workers in the order of their names
+func (s *Supervisor) Statuses() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		statuses = append(statuses, *status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// Failed returns the names of the workers which are failed without a restart
+func (s *Supervisor) Failed() []string {
+	var failed []string
+	for _, status := range s.Statuses() {
+		if status.State == StateFailed {
+			failed = append(failed, status.Name)
+		}
+	}
+
+	return failed
+}
+
+// Handler returns the http.Handler which serves the statuses of the workers as JSON
+func (s *Supervisor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.Statuses())
+	})
+}
@@ -0,0 +1,136 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/logging"
+	"github.com/bilalcaliskan/kube-pod-terminator/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSupervisorRestarts(t *testing.T) {
+	s := New(logging.GetLogger(), 10*time.Millisecond, 40*time.Millisecond, true)
+
+	// the failing worker is restarted with backoff until it succeeds, while the healthy one keeps running
+	var attempts atomic.Int32
+	restarts := testutil.ToFloat64(metrics.WorkerRestarts.WithLabelValues("broken"))
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx, "broken", func(ctx context.Context, logger *zap.Logger) error {
+		if attempts.Add(1) < 4 {
+			return errors.New("credentials are expired")
+		}
+
+		<-ctx.Done()
+		return nil
+	})
+	s.Start(ctx, "healthy", func(ctx context.Context, logger *zap.Logger) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	assert.Eventually(t, func() bool {
+		return attempts.Load() == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	statuses := s.Statuses()
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, "broken", statuses[0].Name)
+	assert.Equal(t, StateRunning, statuses[0].State)
+	assert.Equal(t, 3, statuses[0].Restarts)
+	assert.Equal(t, StateRunning, statuses[1].State)
+	assert.Equal(t, restarts+3, testutil.ToFloat64(metrics.WorkerRestarts.WithLabelValues("broken")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.WorkerUp.WithLabelValues("healthy")))
+
+	cancel()
+	s.Wait()
+	for _, status := range s.Statuses() {
+		assert.Equal(t, StateStopped, status.State)
+	}
+	assert.Empty(t, s.Failed())
+}
+
+func TestSupervisorWithoutRestart(t *testing.T) {
+	s := New(logging.GetLogger(), time.Millisecond, time.Millisecond, false)
+
+	// a panicking worker is failed without affecting the others
+	s.Start(context.Background(), "panicking", func(ctx context.Context, logger *zap.Logger) error {
+		panic("nil map")
+	})
+	s.Start(context.Background(), "completed", func(ctx context.Context, logger *zap.Logger) error {
+		return nil
+	})
+	s.Wait()
+
+	statuses := s.Statuses()
+	assert.Equal(t, StateStopped, statuses[0].State)
+	assert.Equal(t, StateFailed, statuses[1].State)
+	assert.Equal(t, "worker panicked: nil map", statuses[1].LastError)
+	assert.Equal(t, []string{"panicking"}, s.Failed())
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.WorkerUp.WithLabelValues("panicking")))
+}
+
+func TestSupervisorStop(t *testing.T) {
+	s := New(logging.GetLogger(), time.Millisecond, time.Millisecond, true)
+	worker := func(ctx context.Context, logger *zap.Logger) error {
+		<-ctx.Done()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.True(t, s.Start(ctx, "staging", worker))
+	assert.True(t, s.Start(ctx, "production", worker))
+	assert.False(t, s.Start(ctx, "staging", worker))
+	assert.Equal(t, []string{"production", "staging"}, s.Names())
+
+	// a stopped worker is forgotten and can be started again with the same name
+	assert.True(t, s.Stop("staging"))
+	assert.False(t, s.Stop("staging"))
+	assert.Equal(t, []string{"production"}, s.Names())
+	for _, status := range s.Statuses() {
+		assert.Equal(t, "production", status.Name)
+	}
+
+	assert.True(t, s.Start(ctx, "staging", worker))
+	assert.Eventually(t, func() bool {
+		return len(s.Statuses()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	s.Wait()
+	assert.False(t, s.Start(context.Background(), "development", worker))
+}
+
+func TestHandler(t *testing.T) {
+	s := New(logging.GetLogger(), time.Millisecond, time.Millisecond, false)
+	s.Start(context.Background(), "/home/joshsagredo/.kube/config", func(ctx context.Context,
+		logger *zap.Logger) error {
+		return errors.New("connection refused")
+	})
+	s.Wait()
+
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var statuses []Status
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&statuses))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, StateFailed, statuses[0].State)
+	assert.Equal(t, "connection refused", statuses[0].LastError)
+}